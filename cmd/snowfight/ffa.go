@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/rating"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FFAGroup represents a set of bots that play one free-for-all match
+type FFAGroup struct {
+	BotURLs []string
+	Round   int
+	Seed    int64             // Match seed derived from the league seed
+	Storage []json.RawMessage // Stored values of the bots, nil without --state-dir
+}
+
+// FFAPlacement represents one bot's finish in a free-for-all match
+type FFAPlacement struct {
+	Name         string
+	Place        int     // 1-based finishing position, ties share a place
+	Points       float64 // 1 per opponent finished ahead of, 0.5 per tie
+	Winner       bool    // Finished first outright (no tie)
+	EliminatedAt int     // Tick of elimination, 0 if the bot survived
+}
+
+// FFAMatchResult represents the result of a free-for-all match
+type FFAMatchResult struct {
+	BotURLs    []string // The bots of the match in player order
+	Placements []FFAPlacement
+	Error      bool
+	Storage    []json.RawMessage // Stored values the bots left, nil if not kept
+}
+
+// FFAStats tracks free-for-all statistics for each bot
+type FFAStats struct {
+	Name         string
+	Matches      int
+	Wins         int // Matches finished first outright
+	Errors       int
+	TotalPlace   int
+	TotalPoints  float64
+	Eliminations int
+}
+
+// runFFALeague samples bots into free-for-all groups, runs them in parallel, and outputs placement-based rankings.
+// With persistent storage the rounds run one after another, each starting from the values left by the previous round.
+func runFFALeague(botURLs []string, opts LeagueOptions, workers int, storage map[string]json.RawMessage) error {
+	cfg, err := config.Load("config.toml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	groupSize := opts.GroupSize
+	if groupSize > len(botURLs) {
+		groupSize = len(botURLs)
+	}
+
+	seed := cfg.Match.RandomSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	groups := buildFFAGroups(botURLs, groupSize, opts.Rounds, rand.New(rand.NewSource(seed)))
+	for i, g := range groups {
+		if cfg.Match.MaxPlayers > 0 && len(g.BotURLs) > cfg.Match.MaxPlayers {
+			return fmt.Errorf("free-for-all group of %d bots exceeds match.max_players %d", len(g.BotURLs), cfg.Match.MaxPlayers)
+		}
+		// Every match gets its own reproducible seed, like the seeds of the 1v1 pairings
+		groups[i].Seed = deriveSeed(seed, i+1)
+	}
+
+	printLeagueHeader(len(botURLs), len(groups), fmt.Sprintf("Free-for-all (%d rounds, up to %d bots per match)", opts.Rounds, groupSize))
+
+	var results []FFAMatchResult
+	if storage == nil {
+		results = runFFAMatchesParallel(groups, workers)
+	} else {
+		for r := 0; r < opts.Rounds; r++ {
+			var round []FFAGroup
+			for _, g := range groups {
+				if g.Round == r {
+					g.Storage = storageSnapshot(storage, g.BotURLs)
+					round = append(round, g)
+				}
+			}
+			// A bot plays at most once per round, so the order of the results does not matter
+			roundResults := runFFAMatchesParallel(round, workers)
+			for _, result := range roundResults {
+				if result.Storage == nil {
+					continue
+				}
+				updateBotStorage(storage, result.BotURLs, result.Storage)
+			}
+			results = append(results, roundResults...)
+		}
+		if err := saveBotStorage(opts.StateDir, storage); err != nil {
+			return err
+		}
+	}
+	stats := calculateFFAStats(results)
+
+	// Sort by average points per match (descending), then wins, then average place
+	sort.Slice(stats, func(i, j int) bool {
+		avgI := stats[i].averagePoints()
+		avgJ := stats[j].averagePoints()
+		if avgI != avgJ {
+			return avgI > avgJ
+		}
+		if stats[i].Wins != stats[j].Wins {
+			return stats[i].Wins > stats[j].Wins
+		}
+		placeI := stats[i].averagePlace()
+		placeJ := stats[j].averagePlace()
+		if placeI != placeJ {
+			return placeI < placeJ
+		}
+		return stats[i].Name < stats[j].Name
+	})
+
+	fmt.Println("## Rankings")
+	fmt.Println("")
+	fmt.Println("| Rank | Bot | Matches | Wins | Avg Place | Eliminated | Points | Points/Match |")
+	fmt.Println("|------|-----|---------|------|-----------|------------|--------|--------------|")
+
+	for i, s := range stats {
+		fmt.Printf("| %d | `%s` | %d | %d | %.2f | %d | %.1f | %.2f |\n",
+			i+1,
+			s.Name,
+			s.Matches,
+			s.Wins,
+			s.averagePlace(),
+			s.Eliminations,
+			s.TotalPoints,
+			s.averagePoints(),
+		)
+	}
+
+	if opts.RatingsFile != "" {
+		names := make([]string, len(stats))
+		for i, s := range stats {
+			names[i] = s.Name
+		}
+		if err := updateRatings(opts, ffaRatingGames(results), names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ffaRatingGames decomposes free-for-all matches into pairwise games by finishing place.
+func ffaRatingGames(results []FFAMatchResult) []rating.Game {
+	var games []rating.Game
+	for m, result := range results {
+		if result.Error {
+			continue
+		}
+		for i, a := range result.Placements {
+			for _, b := range result.Placements[i+1:] {
+				score := 0.5
+				if a.Place < b.Place {
+					score = 1
+				} else if a.Place > b.Place {
+					score = 0
+				}
+				games = append(games, rating.Game{A: a.Name, B: b.Name, ScoreA: score, Match: m + 1})
+			}
+		}
+	}
+	return games
+}
+
+// buildFFAGroups shuffles the bots every round and splits them into as few groups of at most groupSize as possible,
+// with group sizes differing by at most one so that every bot plays once per round. When that would leave a group
+// of a single bot (an odd number of bots with groupSize 2), one group plays with groupSize+1 bots instead.
+func buildFFAGroups(botURLs []string, groupSize, rounds int, rng *rand.Rand) []FFAGroup {
+	n := len(botURLs)
+	if n < 2 {
+		return nil
+	}
+	count := (n + groupSize - 1) / groupSize
+	if n/count < 2 {
+		count = n / 2
+	}
+
+	var groups []FFAGroup
+	for r := 0; r < rounds; r++ {
+		order := make([]string, n)
+		copy(order, botURLs)
+		rng.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+		start := 0
+		for g := 0; g < count; g++ {
+			size := n / count
+			if g < n%count {
+				size++
+			}
+			end := start + size
+			groups = append(groups, FFAGroup{BotURLs: order[start:end:end], Round: r})
+			start = end
+		}
+	}
+	return groups
+}
+
+// runFFAMatchesParallel runs all free-for-all matches in parallel using a worker pool
+func runFFAMatchesParallel(groups []FFAGroup, workers int) []FFAMatchResult {
+	jobs := make(chan FFAGroup, len(groups))
+	results := make(chan FFAMatchResult, len(groups))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go ffaWorker(jobs, results, &wg)
+	}
+
+	go func() {
+		for _, g := range groups {
+			jobs <- g
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var allResults []FFAMatchResult
+	for result := range results {
+		allResults = append(allResults, result)
+	}
+	return allResults
+}
+
+// ffaWorker processes free-for-all groups from the jobs channel
+func ffaWorker(jobs <-chan FFAGroup, results chan<- FFAMatchResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for group := range jobs {
+		names := make([]string, len(group.BotURLs))
+		for i, url := range group.BotURLs {
+			names[i] = extractBotName(url)
+		}
+
+		var storage []json.RawMessage
+		if group.Storage != nil {
+			storage = append([]json.RawMessage{}, group.Storage...)
+		}
+		var buf bytes.Buffer
+		err := runMatchWithOptions(group.BotURLs, &buf, MatchOptions{Seed: group.Seed, Storage: storage})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Match %s failed: %v\n", strings.Join(names, " vs "), err)
+			results <- FFAMatchResult{Placements: placeholderPlacements(names), Error: true}
+			continue
+		}
+
+		result, ok := readResultRecord(buf.String())
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: Match %s produced no result record\n", strings.Join(names, " vs "))
+			results <- FFAMatchResult{Placements: placeholderPlacements(names), Error: true}
+			continue
+		}
+		results <- FFAMatchResult{BotURLs: group.BotURLs, Placements: rankPlacements(names, result), Storage: storage}
+	}
+}
+
+// placeholderPlacements returns placements without points for a match that could not be scored.
+func placeholderPlacements(names []string) []FFAPlacement {
+	placements := make([]FFAPlacement, len(names))
+	for i, name := range names {
+		placements[i] = FFAPlacement{Name: name}
+	}
+	return placements
+}
+
+// finishedAhead reports whether a finished ahead of b: survivors rank above eliminated bots,
+// survivors are ordered by HP and eliminated bots by how long they survived.
+func finishedAhead(a, b game.PlayerResult) bool {
+	aAlive := a.HP > 0
+	bAlive := b.HP > 0
+	if aAlive != bAlive {
+		return aAlive
+	}
+	if aAlive {
+		return a.HP > b.HP
+	}
+	return a.TicksSurvived > b.TicksSurvived
+}
+
+// rankPlacements converts a match result into finishing positions and placement points.
+func rankPlacements(names []string, result game.Result) []FFAPlacement {
+	placements := make([]FFAPlacement, 0, len(result.Players))
+	for i, p := range result.Players {
+		if i >= len(names) {
+			break
+		}
+		placement := FFAPlacement{Name: names[i], Place: 1}
+		for j, other := range result.Players {
+			if i == j {
+				continue
+			}
+			switch {
+			case finishedAhead(p, other):
+				placement.Points++
+			case finishedAhead(other, p):
+				placement.Place++
+			default:
+				placement.Points += 0.5
+			}
+		}
+		placement.Winner = placement.Points == float64(len(result.Players)-1)
+		if p.HP <= 0 {
+			placement.EliminatedAt = p.TicksSurvived
+		}
+		placements = append(placements, placement)
+	}
+	return placements
+}
+
+// calculateFFAStats aggregates free-for-all placements into bot statistics
+func calculateFFAStats(results []FFAMatchResult) []FFAStats {
+	statsMap := make(map[string]*FFAStats)
+
+	for _, result := range results {
+		for _, placement := range result.Placements {
+			stats, exists := statsMap[placement.Name]
+			if !exists {
+				stats = &FFAStats{Name: placement.Name}
+				statsMap[placement.Name] = stats
+			}
+			if result.Error {
+				stats.Errors++
+				continue
+			}
+			stats.Matches++
+			stats.TotalPlace += placement.Place
+			stats.TotalPoints += placement.Points
+			if placement.Winner {
+				stats.Wins++
+			}
+			if placement.EliminatedAt > 0 {
+				stats.Eliminations++
+			}
+		}
+	}
+
+	var statsList []FFAStats
+	for _, stats := range statsMap {
+		statsList = append(statsList, *stats)
+	}
+	return statsList
+}
+
+func (s FFAStats) averagePoints() float64 {
+	if s.Matches == 0 {
+		return 0
+	}
+	return s.TotalPoints / float64(s.Matches)
+}
+
+func (s FFAStats) averagePlace() float64 {
+	if s.Matches == 0 {
+		return 0
+	}
+	return float64(s.TotalPlace) / float64(s.Matches)
+}
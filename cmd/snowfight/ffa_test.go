@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestBuildFFAGroups_EveryBotPlaysOncePerRound(t *testing.T) {
+	cases := []struct{ bots, groupSize int }{
+		{2, 2}, {3, 2}, {5, 2}, {4, 4}, {5, 4}, {6, 4}, {9, 4}, {7, 3}, {10, 6}, {13, 5},
+	}
+	for _, tc := range cases {
+		var bots []string
+		for i := 0; i < tc.bots; i++ {
+			bots = append(bots, fmt.Sprintf("bot%d.js", i))
+		}
+		const rounds = 3
+		groups := buildFFAGroups(bots, tc.groupSize, rounds, rand.New(rand.NewSource(1)))
+
+		seen := make([]map[string]int, rounds)
+		for r := range seen {
+			seen[r] = make(map[string]int)
+		}
+		minSize, maxSize := tc.bots, 0
+		for _, g := range groups {
+			if len(g.BotURLs) < 2 {
+				t.Errorf("%d bots, group size %d: group of %d bots", tc.bots, tc.groupSize, len(g.BotURLs))
+			}
+			minSize = min(minSize, len(g.BotURLs))
+			maxSize = max(maxSize, len(g.BotURLs))
+			for _, b := range g.BotURLs {
+				seen[g.Round][b]++
+			}
+		}
+		if maxSize-minSize > 1 {
+			t.Errorf("%d bots, group size %d: group sizes range from %d to %d", tc.bots, tc.groupSize, minSize, maxSize)
+		}
+		for r := range seen {
+			for _, b := range bots {
+				if seen[r][b] != 1 {
+					t.Errorf("%d bots, group size %d: %s plays %d times in round %d", tc.bots, tc.groupSize, b, seen[r][b], r)
+				}
+			}
+		}
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/rating"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,13 +20,23 @@ import (
 )
 
 func showLeagueHelp() {
-	fmt.Println("Usage: snowfight league < bots.txt")
+	fmt.Println("Usage: snowfight league [options] < bots.txt")
 	fmt.Println()
 	fmt.Println("Run a league tournament with bots from stdin.")
 	fmt.Println()
 	fmt.Println("Input:")
 	fmt.Println("  One bot URL or file path per line from stdin")
 	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --mode <pairs|ffa>   League format: 1v1 round-robin (default) or free-for-all")
+	fmt.Println("  --games-per-pair <N> Seeds per 1v1 pairing, alternating the player order (default: 1)")
+	fmt.Println("  --swap-sides         Play every seed twice, once with each player order")
+	fmt.Println("  --group-size <K>     Bots per free-for-all match (default: 4)")
+	fmt.Println("  --rounds <R>         Free-for-all rounds; every bot plays once per round (default: 10)")
+	fmt.Println("  --ratings <file>     Update ratings stored in this JSON file and report them")
+	fmt.Println("  --rating-system <s>  Rating system: glicko2 (default) or elo")
+	fmt.Println("  --state-dir <dir>    Keep each bot's storage.get/set value in this directory across matches")
+	fmt.Println()
 	fmt.Println("Environment variables:")
 	fmt.Println("  LEAGUE_WORKERS   Number of parallel workers (default: 8)")
 	fmt.Println()
@@ -31,6 +46,60 @@ func showLeagueHelp() {
 	fmt.Println("Example:")
 	fmt.Println("  snowfight fetch > bots.txt")
 	fmt.Println("  snowfight league < bots.txt")
+	fmt.Println("  snowfight league --mode ffa --group-size 6 < bots.txt")
+}
+
+// LeagueOptions holds command-line options of the league command.
+type LeagueOptions struct {
+	Mode         string // "pairs" or "ffa"
+	GamesPerPair int    // Seeds per pairing
+	SwapSides    bool   // Play every seed with both player orders
+	GroupSize    int
+	Rounds       int
+	RatingsFile  string // Empty disables ratings
+	RatingSystem rating.System
+	StateDir     string // Empty disables persistent bot storage
+}
+
+// parseLeagueOptions parses league command-line flags.
+func parseLeagueOptions(args []string) (LeagueOptions, error) {
+	var opts LeagueOptions
+	fs := flag.NewFlagSet("league", flag.ContinueOnError)
+	fs.Usage = showLeagueHelp
+	fs.StringVar(&opts.Mode, "mode", "pairs", "league format: pairs or ffa")
+	fs.IntVar(&opts.GamesPerPair, "games-per-pair", 1, "seeds per pairing")
+	fs.BoolVar(&opts.SwapSides, "swap-sides", false, "play every seed with both player orders")
+	fs.IntVar(&opts.GroupSize, "group-size", 4, "bots per free-for-all match")
+	fs.IntVar(&opts.Rounds, "rounds", 10, "free-for-all rounds")
+	fs.StringVar(&opts.RatingsFile, "ratings", "", "ratings JSON file")
+	fs.StringVar(&opts.StateDir, "state-dir", "", "directory for persistent bot storage")
+	system := fs.String("rating-system", string(rating.Glicko2), "rating system: glicko2 or elo")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	var err error
+	if opts.RatingSystem, err = rating.ParseSystem(*system); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	switch opts.Mode {
+	case "pairs":
+		if opts.GamesPerPair < 1 {
+			return opts, fmt.Errorf("--games-per-pair must be at least 1 (got %d)", opts.GamesPerPair)
+		}
+	case "ffa":
+		if opts.GroupSize < 2 {
+			return opts, fmt.Errorf("--group-size must be at least 2 (got %d)", opts.GroupSize)
+		}
+		if opts.Rounds < 1 {
+			return opts, fmt.Errorf("--rounds must be at least 1 (got %d)", opts.Rounds)
+		}
+	default:
+		return opts, fmt.Errorf("unknown league mode %q (want pairs or ffa)", opts.Mode)
+	}
+	return opts, nil
 }
 
 // BotStats tracks statistics for each bot
@@ -48,15 +117,22 @@ type BotStats struct {
 type MatchPair struct {
 	Bot1URL string
 	Bot2URL string
+	Seed    int64
+	Index   int               // Position in the schedule
+	Storage []json.RawMessage // Stored values of both bots, nil without --state-dir
 }
 
 // MatchResult represents the result of a match
 type MatchResult struct {
 	Bot1Name string
 	Bot2Name string
-	Winner   string // "Bot1", "Bot2", "DRAW", "ERROR"
+	Winner   string // "P1", "P2", "DRAW", "ERROR", "UNKNOWN"
+	Reason   string // End reason from the match result record
+	Seed     int64
 	Bot1HP   int
 	Bot2HP   int
+	Index    int               // Position of the match in the schedule
+	Storage  []json.RawMessage // Stored values both bots left, nil if not kept
 }
 
 // runLeague reads bot URLs from stdin, runs round-robin tournament in parallel, and outputs ranked results.
@@ -67,6 +143,11 @@ func runLeague(args []string) error {
 		return nil
 	}
 
+	opts, err := parseLeagueOptions(args)
+	if err != nil {
+		return err
+	}
+
 	// Read bot URLs from stdin
 	var botURLs []string
 	scanner := bufio.NewScanner(os.Stdin)
@@ -91,46 +172,75 @@ func runLeague(args []string) error {
 	// Get worker count from environment variable
 	workers := getWorkerCount()
 
-	// Generate all match pairs (round-robin)
+	var storage map[string]json.RawMessage
+	if opts.StateDir != "" {
+		if storage, err = loadBotStorage(opts.StateDir, botURLs); err != nil {
+			return err
+		}
+	}
+
+	if opts.Mode == "ffa" {
+		return runFFALeague(botURLs, opts, workers, storage)
+	}
+
+	// Generate all match pairs (round-robin), each played on every derived seed. The player order
+	// alternates between seeds, or every seed is played with both orders with --swap-sides.
+	baseSeed := leagueBaseSeed()
 	var allPairs []MatchPair
 	for i := 0; i < len(botURLs); i++ {
 		for j := i + 1; j < len(botURLs); j++ {
-			allPairs = append(allPairs, MatchPair{
-				Bot1URL: botURLs[i],
-				Bot2URL: botURLs[j],
-			})
+			for k := 0; k < opts.GamesPerPair; k++ {
+				seed := deriveSeed(baseSeed, k)
+				first, second := botURLs[i], botURLs[j]
+				if k%2 == 1 {
+					first, second = second, first
+				}
+				allPairs = append(allPairs, MatchPair{Bot1URL: first, Bot2URL: second, Seed: seed})
+				if opts.SwapSides {
+					allPairs = append(allPairs, MatchPair{Bot1URL: second, Bot2URL: first, Seed: seed})
+				}
+			}
 		}
 	}
 
-	totalMatches := len(allPairs)
-
-	// Output header
-	fmt.Printf("# SnowFight League Results\n\n")
-	fmt.Printf("**Date**: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Printf("- **Total Bots**: %d\n", len(botURLs))
-	fmt.Printf("- **Total Matches**: %d\n\n", totalMatches)
+	// The round-robin is a single round: every match starts from the stored values loaded above
+	for i := range allPairs {
+		allPairs[i].Index = i
+		if storage != nil {
+			allPairs[i].Storage = storageSnapshot(storage, []string{allPairs[i].Bot1URL, allPairs[i].Bot2URL})
+		}
+	}
 
-	// Output config
-	configContent, err := readConfigForDisplay()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not read config.toml: %v\n", err)
-	} else {
-		fmt.Printf("## Match Configuration\n\n")
-		fmt.Printf("```toml\n%s\n```\n\n", strings.TrimSpace(configContent))
+	format := fmt.Sprintf("Round-robin (%d seed(s) per pairing)", opts.GamesPerPair)
+	if opts.SwapSides {
+		format = fmt.Sprintf("Round-robin (%d seed(s) per pairing, both player orders)", opts.GamesPerPair)
 	}
+	printLeagueHeader(len(botURLs), len(allPairs), format)
 
 	// Run matches in parallel
 	results := runMatchesParallel(allPairs, workers)
 
+	// A bot keeps the value left by its last match in schedule order
+	if storage != nil {
+		sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+		for _, result := range results {
+			if result.Storage != nil {
+				pair := allPairs[result.Index]
+				updateBotStorage(storage, []string{pair.Bot1URL, pair.Bot2URL}, result.Storage)
+			}
+		}
+		if err := saveBotStorage(opts.StateDir, storage); err != nil {
+			return err
+		}
+	}
+
 	// Calculate bot statistics
 	botStats := calculateBotStats(results)
 
 	// Sort by win rate (descending), then by total HP
 	sort.Slice(botStats, func(i, j int) bool {
-		totalI := botStats[i].Wins + botStats[i].Losses + botStats[i].Draws
-		totalJ := botStats[j].Wins + botStats[j].Losses + botStats[j].Draws
-		winRateI := float64(botStats[i].Wins) / float64(totalI)
-		winRateJ := float64(botStats[j].Wins) / float64(totalJ)
+		winRateI := botStats[i].winRate()
+		winRateJ := botStats[j].winRate()
 
 		if winRateI != winRateJ {
 			return winRateI > winRateJ
@@ -146,25 +256,128 @@ func runLeague(args []string) error {
 	// Output rankings
 	fmt.Println("## Rankings")
 	fmt.Println("")
-	fmt.Println("| Rank | Bot | Wins | Losses | Draws | Win Rate |")
-	fmt.Println("|------|-----|------|--------|-------|----------|")
+	fmt.Println("| Rank | Bot | Wins | Losses | Draws | Win Rate | 95% CI (draw = ½ win) |")
+	fmt.Println("|------|-----|------|--------|-------|----------|--------|")
 
 	for i, stats := range botStats {
-		total := stats.Wins + stats.Losses + stats.Draws
-		winRate := float64(stats.Wins) / float64(total) * 100
-		fmt.Printf("| %d | `%s` | %d | %d | %d | %.1f%% |\n",
+		low, high := wilsonInterval(float64(stats.Wins)+float64(stats.Draws)/2, stats.games())
+		fmt.Printf("| %d | `%s` | %d | %d | %d | %.1f%% | %.1f%%–%.1f%% |\n",
 			i+1,
 			stats.Name,
 			stats.Wins,
 			stats.Losses,
 			stats.Draws,
-			winRate,
+			stats.winRate()*100,
+			low*100,
+			high*100,
+		)
+	}
+
+	if opts.RatingsFile != "" {
+		var games []rating.Game
+		for _, result := range results {
+			switch result.Winner {
+			case "P1":
+				games = append(games, rating.Game{A: result.Bot1Name, B: result.Bot2Name, ScoreA: 1})
+			case "P2":
+				games = append(games, rating.Game{A: result.Bot1Name, B: result.Bot2Name, ScoreA: 0})
+			case "DRAW":
+				games = append(games, rating.Game{A: result.Bot1Name, B: result.Bot2Name, ScoreA: 0.5})
+			}
+		}
+		names := make([]string, len(botStats))
+		for i, stats := range botStats {
+			names[i] = stats.Name
+		}
+		if err := updateRatings(opts, games, names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateRatings applies this run's games to the ratings file and outputs a ratings table for the given bots.
+func updateRatings(opts LeagueOptions, games []rating.Game, names []string) error {
+	table, err := rating.Load(opts.RatingsFile, opts.RatingSystem)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]rating.Rating, len(table.Ratings))
+	for name, r := range table.Ratings {
+		previous[name] = r
+	}
+
+	table.Update(games)
+	table.Updated = time.Now().Format("2006-01-02 15:04:05")
+	if err := table.Save(opts.RatingsFile); err != nil {
+		return err
+	}
+
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.Slice(sorted, func(i, j int) bool {
+		ri := table.Get(sorted[i]).Rating
+		rj := table.Get(sorted[j]).Rating
+		if ri != rj {
+			return ri > rj
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	systemName := "Elo"
+	if opts.RatingSystem == rating.Glicko2 {
+		systemName = "Glicko-2"
+	}
+	fmt.Println("")
+	fmt.Printf("## Ratings (%s)\n", systemName)
+	fmt.Println("")
+	fmt.Println("| Rank | Bot | Rating | Deviation | Change | Games |")
+	fmt.Println("|------|-----|--------|-----------|--------|-------|")
+
+	for i, name := range sorted {
+		r := table.Get(name)
+		deviation := "-"
+		if opts.RatingSystem == rating.Glicko2 {
+			deviation = fmt.Sprintf("%.0f", r.Deviation)
+		}
+		change := "new"
+		if prev, ok := previous[name]; ok {
+			change = fmt.Sprintf("%+.0f", r.Rating-prev.Rating)
+		}
+		fmt.Printf("| %d | `%s` | %.0f | %s | %s | %d |\n",
+			i+1,
+			name,
+			r.Rating,
+			deviation,
+			change,
+			r.Games,
 		)
 	}
 
 	return nil
 }
 
+// printLeagueHeader outputs the Markdown title, summary and match configuration.
+func printLeagueHeader(totalBots, totalMatches int, format string) {
+	fmt.Printf("# SnowFight League Results\n\n")
+	fmt.Printf("**Date**: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	if format != "" {
+		fmt.Printf("- **Format**: %s\n", format)
+	}
+	fmt.Printf("- **Total Bots**: %d\n", totalBots)
+	fmt.Printf("- **Total Matches**: %d\n\n", totalMatches)
+
+	// Output config
+	configContent, err := readConfigForDisplay()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read config.toml: %v\n", err)
+	} else {
+		fmt.Printf("## Match Configuration\n\n")
+		fmt.Printf("```toml\n%s\n```\n\n", strings.TrimSpace(configContent))
+	}
+}
+
 // readConfigForDisplay reads config.toml and returns it as a formatted string
 func readConfigForDisplay() (string, error) {
 	data, err := os.ReadFile("config.toml")
@@ -236,7 +449,11 @@ func matchWorker(jobs <-chan MatchPair, results chan<- MatchResult, wg *sync.Wai
 		var buf bytes.Buffer
 		matchArgs := []string{pair.Bot1URL, pair.Bot2URL}
 
-		err := runMatchWithWriter(matchArgs, &buf)
+		var storage []json.RawMessage
+		if pair.Storage != nil {
+			storage = append([]json.RawMessage{}, pair.Storage...)
+		}
+		err := runMatchWithOptions(matchArgs, &buf, MatchOptions{Seed: pair.Seed, Storage: storage})
 
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Match %s vs %s failed: %v\n", bot1Name, bot2Name, err)
@@ -244,21 +461,28 @@ func matchWorker(jobs <-chan MatchPair, results chan<- MatchResult, wg *sync.Wai
 				Bot1Name: bot1Name,
 				Bot2Name: bot2Name,
 				Winner:   "ERROR",
+				Reason:   game.EndError,
+				Seed:     pair.Seed,
 				Bot1HP:   0,
 				Bot2HP:   0,
+				Index:    pair.Index,
 			}
 			continue
 		}
 
-		// Parse match output to get final state
-		winner, bot1HP, bot2HP := parseMatchResult(buf.String())
+		// Parse the result record of the match output
+		winner, reason, bot1HP, bot2HP := parseMatchResult(buf.String())
 
 		results <- MatchResult{
 			Bot1Name: bot1Name,
 			Bot2Name: bot2Name,
 			Winner:   winner,
+			Reason:   reason,
+			Seed:     pair.Seed,
 			Bot1HP:   bot1HP,
 			Bot2HP:   bot2HP,
+			Index:    pair.Index,
+			Storage:  storage,
 		}
 	}
 }
@@ -303,6 +527,60 @@ func calculateBotStats(results []MatchResult) []BotStats {
 	return statsList
 }
 
+// games returns the number of scored games (errors excluded)
+func (s BotStats) games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// winRate returns the fraction of scored games won
+func (s BotStats) winRate() float64 {
+	if s.games() == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.games())
+}
+
+// wilsonInterval returns the 95% Wilson score confidence interval for a score out of n games,
+// where a draw counts as half a win
+func wilsonInterval(score float64, n int) (low, high float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	const z = 1.96
+	p := score / float64(n)
+	nf := float64(n)
+	denom := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denom
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// leagueBaseSeed returns match.random_seed from config.toml, or a time-based seed if unset
+func leagueBaseSeed() int64 {
+	cfg, _ := config.Load("config.toml")
+	if cfg.Match.RandomSeed != 0 {
+		return cfg.Match.RandomSeed
+	}
+	return time.Now().UnixNano()
+}
+
+// deriveSeed returns the k-th match seed derived from base (k=0 is base itself).
+// Seeds are mixed with SplitMix64 so neighbouring k give unrelated spawns; zero is avoided
+// because it means "time-based" to the engine.
+func deriveSeed(base int64, k int) int64 {
+	if k == 0 {
+		return base
+	}
+	z := uint64(base) + uint64(k)*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	if z == 0 {
+		z = 1
+	}
+	return int64(z)
+}
+
 // extractBotName extracts a readable bot name from a URL or file path
 func extractBotName(url string) string {
 	// For URLs like: https://raw.githubusercontent.com/owner/repo/branch/file.js
@@ -324,44 +602,49 @@ func extractBotName(url string) string {
 	return fmt.Sprintf("%s/%s", dir, fileName)
 }
 
-// parseMatchResult parses JSONL output to extract winner and final HP
-func parseMatchResult(jsonlOutput string) (winner string, p1HP int, p2HP int) {
+// readResultRecord finds the {"type":"result"} record in JSONL match output.
+func readResultRecord(jsonlOutput string) (game.Result, bool) {
 	lines := strings.Split(strings.TrimSpace(jsonlOutput), "\n")
-
-	// Find the last state record
 	for i := len(lines) - 1; i >= 0; i-- {
-		var record map[string]interface{}
+		var record struct {
+			Type string `json:"type"`
+			game.Result
+		}
 		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
 			continue
 		}
+		if record.Type == "result" {
+			return record.Result, true
+		}
+	}
+	return game.Result{}, false
+}
 
-		if recordType, ok := record["type"].(string); ok && recordType == "state" {
-			// Extract player data
-			if players, ok := record["players"].([]interface{}); ok && len(players) >= 2 {
-				if p1, ok := players[0].(map[string]interface{}); ok {
-					if hp, ok := p1["hp"].(float64); ok {
-						p1HP = int(hp)
-					}
-				}
-				if p2, ok := players[1].(map[string]interface{}); ok {
-					if hp, ok := p2["hp"].(float64); ok {
-						p2HP = int(hp)
-					}
-				}
-			}
+// parseMatchResult parses JSONL output of a two-player match to extract winner, end reason and final HP
+func parseMatchResult(jsonlOutput string) (winner string, reason string, p1HP int, p2HP int) {
+	result, ok := readResultRecord(jsonlOutput)
+	if !ok {
+		return "UNKNOWN", "", 0, 0
+	}
 
-			// Determine winner
-			if p1HP > p2HP {
-				winner = "P1"
-			} else if p2HP > p1HP {
-				winner = "P2"
-			} else {
-				winner = "DRAW"
-			}
-			return
+	for _, p := range result.Players {
+		switch p.ID {
+		case 1:
+			p1HP = p.HP
+		case 2:
+			p2HP = p.HP
 		}
 	}
 
-	// If we couldn't parse, return unknown
-	return "UNKNOWN", 0, 0
+	switch {
+	case result.Reason == game.EndError:
+		winner = "ERROR"
+	case len(result.Winners) == 1 && result.Winners[0] == 1:
+		winner = "P1"
+	case len(result.Winners) == 1 && result.Winners[0] == 2:
+		winner = "P2"
+	default:
+		winner = "DRAW"
+	}
+	return winner, result.Reason, p1HP, p2HP
 }
@@ -13,8 +13,10 @@ func showHelp() {
 	fmt.Println("Available commands:")
 	fmt.Println("  match       Run a match between bots")
 	fmt.Println("  visualize   Generate HTML visualization from match output")
+	fmt.Println("  replay      Replay a match log in the terminal")
 	fmt.Println("  fetch       Fetch bot URLs from GitHub repositories")
 	fmt.Println("  league      Run a league tournament from bot URLs")
+	fmt.Println("  verify      Re-simulate a match log and check it matches")
 	fmt.Println()
 	fmt.Println("Use 'snowfight <command> -h' for more information about a command.")
 }
@@ -42,6 +44,11 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+	case "replay":
+		if err := runReplay(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "fetch":
 		if err := runFetch(os.Args[2:]); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -52,6 +59,11 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+	case "verify":
+		if err := runVerify(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Unknown command: %s\n\n", os.Args[1])
 		showHelp()
@@ -1,39 +1,121 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"snowfight/internal/config"
 	"snowfight/internal/game"
 	"snowfight/internal/js"
+	"snowfight/internal/proc"
+	_ "snowfight/internal/wasm" // registers the .wasm bot runtime
+	"strconv"
 	"strings"
 )
 
 func showMatchHelp() {
-	fmt.Println("Usage: snowfight match <js-file-1> <js-file-2> ... <js-file-N>")
+	fmt.Println("Usage: snowfight match [options] <js-file-1> <js-file-2> ... <js-file-N>")
 	fmt.Println()
 	fmt.Println("Run a match between bot scripts.")
 	fmt.Println()
 	fmt.Println("Arguments:")
-	fmt.Println("  <js-file>   Path or URL to a bot JavaScript file")
+	fmt.Println("  <js-file>   Path or URL to a bot file: JavaScript (.js) or WebAssembly (.wasm)")
+	fmt.Println("              js:<file> or wasm:<file> selects the runtime regardless of the extension")
+	fmt.Println("              proc:<command> runs a program that talks the stdio bot protocol")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --teams T1,T2,...   Team number of each bot in order (e.g. 1,1,2,2); 0 = no team")
+	fmt.Println("  --group G1,G2,...   Radio group tag of each bot in order; send() only reaches the same group")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  snowfight match bot1.js bot2.js")
 	fmt.Println("  snowfight match https://example.com/bot1.js bot2.js")
+	fmt.Println("  snowfight match --teams 1,1,2,2 a.js b.js c.js d.js")
+	fmt.Println("  snowfight match proc:./mybot js:other.js")
 	fmt.Println()
 	fmt.Println("Output:")
-	fmt.Println("  JSONL format with match state for each tick")
+	fmt.Println("  JSONL format with match state for each tick, ending with a result record")
+}
+
+// MatchOptions holds per-match settings that override config.toml.
+type MatchOptions struct {
+	Seed  int64 // Non-zero overrides match.random_seed
+	Teams []int // Team number per bot (0 = no team); empty means no teams
+	// Groups is the radio group tag per bot; empty means every bot hears every other bot.
+	Groups []string
+	// Storage enables storage.get/set when non-nil and holds the stored value per bot (nil for none).
+	// When the match ends each entry is replaced by the value the bot left in storage.
+	Storage []json.RawMessage
 }
 
 func runMatch(args []string) error {
-	return runMatchWithWriter(args, os.Stdout)
+	// Check for help flags
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		showMatchHelp()
+		return nil
+	}
+
+	fs := flag.NewFlagSet("match", flag.ContinueOnError)
+	fs.Usage = showMatchHelp
+	teams := fs.String("teams", "", "comma-separated team number per bot")
+	groups := fs.String("group", "", "comma-separated radio group tag per bot")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var opts MatchOptions
+	if *teams != "" {
+		var err error
+		if opts.Teams, err = parseTeams(*teams); err != nil {
+			return err
+		}
+	}
+	if *groups != "" {
+		opts.Groups = strings.Split(*groups, ",")
+	}
+	return runMatchWithOptions(fs.Args(), os.Stdout, opts)
 }
 
-func runMatchWithWriter(args []string, output io.Writer) error {
+// parseTeams parses a --teams value such as "1,1,2,2".
+func parseTeams(value string) ([]int, error) {
+	var teams []int
+	for _, field := range strings.Split(value, ",") {
+		team, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || team < 0 {
+			return nil, fmt.Errorf("invalid --teams entry %q (want a non-negative team number)", field)
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+// validateTeams checks that teams assigns every bot and leaves at least two sides to fight.
+func validateTeams(teams []int, numBots int) error {
+	if len(teams) != numBots {
+		return fmt.Errorf("--teams has %d entries for %d bots", len(teams), numBots)
+	}
+	sides := map[int]bool{}
+	for i, team := range teams {
+		if team == 0 {
+			sides[-(i + 1)] = true
+		} else {
+			sides[team] = true
+		}
+	}
+	if len(sides) < 2 {
+		return fmt.Errorf("--teams must put the bots on at least two sides")
+	}
+	return nil
+}
+
+func runMatchWithOptions(args []string, output io.Writer, opts MatchOptions) error {
 	// Check for help flags
 	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
 		showMatchHelp()
@@ -52,43 +134,82 @@ func runMatchWithWriter(args []string, output io.Writer) error {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
+	if opts.Seed != 0 {
+		cfg.Match.RandomSeed = opts.Seed
+	}
 
 	if cfg.Match.MaxPlayers > 0 && len(args) > cfg.Match.MaxPlayers {
 		return fmt.Errorf("too many players: %d (max %d)", len(args), cfg.Match.MaxPlayers)
 	}
+	if len(opts.Teams) > 0 {
+		if err := validateTeams(opts.Teams, len(args)); err != nil {
+			return err
+		}
+	}
+	if len(opts.Groups) > 0 && len(opts.Groups) != len(args) {
+		return fmt.Errorf("--group has %d entries for %d bots", len(opts.Groups), len(args))
+	}
+	if opts.Storage != nil && len(opts.Storage) != len(args) {
+		return fmt.Errorf("storage has %d entries for %d bots", len(opts.Storage), len(args))
+	}
 
-	runtimes := make([]*js.QuickJSRuntime, len(args))
+	runtimes := make([]js.Runtime, len(args))
+	botHashes := make([]string, len(args))
+	// Registered before loading so that bots loaded before a failing one are closed too
+	defer func() {
+		for _, rt := range runtimes {
+			if rt != nil {
+				rt.Close()
+			}
+		}
+	}()
 	for i, file := range args {
-		code, err := readCode(file)
+		code, hash, err := readBot(file)
 		if err != nil {
 			return fmt.Errorf("failed to read %s: %w", file, err)
 		}
-		rt := js.NewQuickJSRuntime(cfg, i+1)
+		botHashes[i] = hash
+		rt, err := js.NewRuntime(file, cfg, i+1)
+		if err != nil {
+			return err
+		}
+		if opts.Storage != nil {
+			rt.SetStorage(opts.Storage[i])
+		}
 		if err := rt.Load(string(code)); err != nil {
 			rt.Close()
 			return fmt.Errorf("failed to load %s: %w", file, err)
 		}
 		runtimes[i] = rt
 	}
-	defer func() {
-		for _, rt := range runtimes {
-			if rt != nil {
-				rt.Close()
-			}
-		}
-	}()
 
 	engine := game.NewGame(cfg, len(args))
+	engine.SetTeams(opts.Teams)
 
 	// Output metadata record
 	botNames := make([]string, len(args))
 	for i, arg := range args {
-		base := filepath.Base(arg)
-		botNames[i] = strings.TrimSuffix(base, filepath.Ext(base))
+		botNames[i] = botName(arg)
 	}
+	// Record everything needed to re-simulate the match (see 'snowfight verify')
+	effective := *cfg
+	effective.Match.RandomSeed = engine.Seed
 	metaRecord := map[string]interface{}{
-		"type":     "meta",
-		"botNames": botNames,
+		"type":       "meta",
+		"botNames":   botNames,
+		"botHashes":  botHashes,
+		"seed":       engine.Seed,
+		"configHash": effective.Hash(),
+		"obstacles":  engine.Obstacles,
+	}
+	if len(opts.Teams) > 0 {
+		metaRecord["teams"] = opts.Teams
+	}
+	if len(opts.Groups) > 0 {
+		metaRecord["groups"] = opts.Groups
+	}
+	if opts.Storage != nil {
+		metaRecord["storage"] = opts.Storage
 	}
 	if metaBytes, err := json.Marshal(metaRecord); err == nil {
 		fmt.Fprintln(output, string(metaBytes))
@@ -102,12 +223,24 @@ func runMatchWithWriter(args []string, output io.Writer) error {
 
 		actions := make([][]game.Action, len(runtimes))
 		var warnings []js.Warning
+		var sent []js.RadioMessage
 		for idx, rt := range runtimes {
+			// Eliminated bots no longer run
+			if p := engine.State.PlayerRef(idx + 1); p == nil || !p.Alive() {
+				continue
+			}
 			act, w, err := rt.Run(stateForScripts)
 			if err != nil {
-				return fmt.Errorf("error running player %d: %w", idx+1, err)
+				err = fmt.Errorf("error running player %d: %w", idx+1, err)
+				result := engine.Result()
+				result.Reason = game.EndError
+				result.Winners = []int{}
+				result.Error = err.Error()
+				writeResultRecord(output, result)
+				return err
 			}
 			actions[idx] = act
+			sent = append(sent, rt.Sent()...)
 			for _, warn := range w {
 				warn.Tick = stateForScripts.Tick
 				warnings = append(warnings, warn)
@@ -130,11 +263,53 @@ func runMatchWithWriter(args []string, output io.Writer) error {
 				"args":         w.Args,
 				"warning":      w.Warning,
 			}
+			if w.Limit != "" {
+				record["limit"] = w.Limit
+				record["instructionsUsed"] = w.InstructionsUsed
+				record["instructionBudget"] = w.InstructionBudget
+			}
 			j, _ := json.Marshal(record)
 			fmt.Fprintln(output, string(j))
 			fmt.Fprintf(os.Stderr, "Warning: Player %d, %s\n", w.Player, w.Warning)
 		}
 
+		// Route radio messages to the next tick and log them for replay
+		inboxes := make([][]js.RadioMessage, len(runtimes))
+		for _, m := range sent {
+			to := []int{}
+			for idx := range runtimes {
+				if radioReaches(opts.Groups, m.From, idx+1) {
+					inboxes[idx] = append(inboxes[idx], m)
+					to = append(to, idx+1)
+				}
+			}
+			record := map[string]interface{}{
+				"type":    "radio",
+				"tick":    stateForScripts.Tick,
+				"from":    m.From,
+				"to":      to,
+				"channel": m.Channel,
+				"message": m.Message,
+			}
+			j, _ := json.Marshal(record)
+			fmt.Fprintln(output, string(j))
+		}
+		for idx, rt := range runtimes {
+			rt.Deliver(inboxes[idx])
+		}
+
+		// Output an eliminated record for each bot knocked out this tick
+		for _, el := range engine.Eliminations() {
+			record := map[string]interface{}{
+				"type":   "eliminated",
+				"tick":   el.Tick,
+				"player": el.PlayerID,
+				"by":     el.By,
+			}
+			j, _ := json.Marshal(record)
+			fmt.Fprintln(output, string(j))
+		}
+
 		// Output state record with Type="state" after update
 		stateRecord := map[string]interface{}{
 			"type":      "state",
@@ -144,6 +319,12 @@ func runMatchWithWriter(args []string, output io.Writer) error {
 			"p2":        engine.State.P2,
 			"snowballs": engine.State.Snowballs,
 		}
+		if engine.State.Zone != nil {
+			stateRecord["zone"] = engine.State.Zone
+		}
+		if cfg.Piles.Enabled {
+			stateRecord["piles"] = engine.State.Piles
+		}
 		bytes, err := json.Marshal(stateRecord)
 		if err != nil {
 			return fmt.Errorf("json marshal error: %w", err)
@@ -156,9 +337,114 @@ func runMatchWithWriter(args []string, output io.Writer) error {
 		}
 	}
 
+	if opts.Storage != nil {
+		for i, rt := range runtimes {
+			opts.Storage[i] = rt.Storage()
+		}
+	}
+
+	writeResultRecord(output, engine.Result())
 	return nil
 }
 
+// radioReaches reports whether a message from player from is delivered to player to:
+// never to the sender itself, and only within the same group when groups are set.
+func radioReaches(groups []string, from, to int) bool {
+	if from == to {
+		return false
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	return groups[from-1] == groups[to-1]
+}
+
+// writeResultRecord outputs the final {"type":"result"} record of a match.
+func writeResultRecord(output io.Writer, result game.Result) {
+	record := map[string]interface{}{
+		"type":    "result",
+		"tick":    result.Tick,
+		"winners": result.Winners,
+		"reason":  result.Reason,
+		"seed":    result.Seed,
+		"players": result.Players,
+	}
+	if result.Error != "" {
+		record["error"] = result.Error
+	}
+	if bytes, err := json.Marshal(record); err == nil {
+		fmt.Fprintln(output, string(bytes))
+	}
+}
+
+// readBot returns what the runtime of a bot spec loads and the hash recorded for it in the
+// meta record. For proc: bots that is the command line, and the hash also covers the program
+// and the files named on the command line (e.g. the script an interpreter runs).
+func readBot(spec string) ([]byte, string, error) {
+	scheme, target := js.SplitSpec(spec)
+	if scheme != proc.Scheme {
+		code, err := readCode(target)
+		if err != nil {
+			return nil, "", err
+		}
+		return code, sourceHash(code), nil
+	}
+
+	files, err := commandFiles(target)
+	if err != nil {
+		return nil, "", err
+	}
+	hashed := []byte(target)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, "", err
+		}
+		hashed = append(append(hashed, '\n'), data...)
+	}
+	return []byte(target), sourceHash(hashed), nil
+}
+
+// commandFiles returns the program of a command line followed by the arguments naming files.
+func commandFiles(command string) ([]string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	program, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	files := []string{program}
+	for _, arg := range fields[1:] {
+		if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+			files = append(files, arg)
+		}
+	}
+	return files, nil
+}
+
+// botName returns the name of a bot spec shown in match output: its file name without extension.
+// proc: bots are named after the last file on their command line, such as the script they run.
+func botName(spec string) string {
+	scheme, target := js.SplitSpec(spec)
+	if scheme == proc.Scheme {
+		files, err := commandFiles(target)
+		if err != nil {
+			return target
+		}
+		target = files[len(files)-1]
+	}
+	base := filepath.Base(target)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// sourceHash returns the hex SHA-256 of a bot's source code.
+func sourceHash(code []byte) string {
+	sum := sha256.Sum256(code)
+	return hex.EncodeToString(sum[:])
+}
+
 func readCode(pathOrURL string) ([]byte, error) {
 	if strings.HasPrefix(pathOrURL, "http://") || strings.HasPrefix(pathOrURL, "https://") {
 		resp, err := http.Get(pathOrURL)
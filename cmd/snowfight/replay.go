@@ -0,0 +1,635 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"strings"
+	"time"
+)
+
+func showReplayHelp() {
+	fmt.Println("Usage: snowfight replay --tui [options] <match-log-file>")
+	fmt.Println("       snowfight replay --tui [options] - < match.jsonl")
+	fmt.Println()
+	fmt.Println("Replay a match log in the terminal, without a browser.")
+	fmt.Println()
+	fmt.Println("The field is drawn as a character grid: players are their number with an arrow for")
+	fmt.Println("their heading, o is a snowball, * a snowball pile, # an obstacle and . lies outside")
+	fmt.Println("the safe zone. HP bars and the warning, radio and elimination records are shown alongside.")
+	fmt.Println("The field size is read from config.toml (defaults when it is missing).")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --tui          Use the terminal viewer")
+	fmt.Println("  --width N      Columns of the field grid (default 60)")
+	fmt.Println("  --tick N       Start at tick N")
+	fmt.Println("  --fps N        Ticks per second while playing (default 10)")
+	fmt.Println("  --print        Print frames instead of playing interactively (for CI logs)")
+	fmt.Println("  --every N      With --print, print every N-th tick and the last one (default 50)")
+	fmt.Println("  --no-color     Disable ANSI colors (also disabled by the NO_COLOR variable)")
+	fmt.Println()
+	fmt.Println("Keys:")
+	fmt.Println("  space          Play / pause")
+	fmt.Println("  right, l       Step forward one tick")
+	fmt.Println("  left, h        Step back one tick")
+	fmt.Println("  up, k          Seek forward 50 ticks")
+	fmt.Println("  down, j        Seek back 50 ticks")
+	fmt.Println("  home, g        Seek to the first tick")
+	fmt.Println("  end, G         Seek to the last tick")
+	fmt.Println("  +, -           Play faster / slower")
+	fmt.Println("  q              Quit")
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Println("  snowfight match bot1.js bot2.js > match.jsonl")
+	fmt.Println("  snowfight replay --tui match.jsonl")
+}
+
+// replayEvent is a warning, radio or eliminated record, formatted for the log panel.
+type replayEvent struct {
+	Tick    int
+	Text    string
+	Warning bool
+}
+
+// replayLog holds the records of a match log needed to replay it.
+type replayLog struct {
+	Names     []string
+	Teams     []int
+	Obstacles []game.Obstacle
+	States    []game.GameState
+	Events    []replayEvent
+	Result    *game.Result
+}
+
+func runReplay(args []string) error {
+	// Check for help flags
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		showReplayHelp()
+		return nil
+	}
+
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = showReplayHelp
+	tui := fs.Bool("tui", false, "use the terminal viewer")
+	width := fs.Int("width", 60, "columns of the field grid")
+	startTick := fs.Int("tick", 0, "tick to start at")
+	fps := fs.Int("fps", 10, "ticks per second while playing")
+	printFrames := fs.Bool("print", false, "print frames instead of playing interactively")
+	every := fs.Int("every", 50, "with --print, print every N-th tick")
+	noColor := fs.Bool("no-color", false, "disable ANSI colors")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*tui {
+		return fmt.Errorf("usage: snowfight replay --tui <match-log-file> (the terminal viewer is the only one)")
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: snowfight replay --tui <match-log-file>")
+	}
+	if *width < 20 || *fps < 1 || *every < 1 {
+		return fmt.Errorf("--width must be at least 20, --fps and --every at least 1")
+	}
+
+	logContent, err := readVisualizeInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	replay, err := parseReplayLog(logContent)
+	if err != nil {
+		return err
+	}
+
+	// Like the HTML visualizer, the log does not record the field size
+	cfg, _ := config.Load("config.toml")
+	view := newReplayView(replay, cfg, *width, !*noColor && os.Getenv("NO_COLOR") == "")
+
+	start := 0
+	for i, s := range replay.States {
+		if s.Tick <= *startTick {
+			start = i
+		}
+	}
+	if *printFrames {
+		return view.print(os.Stdout, start, *every)
+	}
+	return view.play(start, *fps, fs.NArg() == 0 || fs.Arg(0) == "-")
+}
+
+// parseReplayLog reads the meta, state, event and result records of a match log.
+func parseReplayLog(content string) (*replayLog, error) {
+	replay := &replayLog{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var rec struct {
+			Type      string          `json:"type"`
+			Tick      int             `json:"tick"`
+			BotNames  []string        `json:"botNames"`
+			Teams     []int           `json:"teams"`
+			Obstacles []game.Obstacle `json:"obstacles"`
+
+			// warning
+			Player  int    `json:"warnedPlayer"`
+			API     string `json:"api"`
+			Warning string `json:"warning"`
+
+			// eliminated
+			Eliminated int `json:"player"`
+			By         int `json:"by"`
+
+			// radio
+			From    int             `json:"from"`
+			Channel string          `json:"channel"`
+			Message json.RawMessage `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch rec.Type {
+		case "meta":
+			replay.Names = rec.BotNames
+			replay.Teams = rec.Teams
+			replay.Obstacles = rec.Obstacles
+		case "warning":
+			text := fmt.Sprintf("%s: %s - %s", replay.name(rec.Player), rec.API, rec.Warning)
+			replay.Events = append(replay.Events, replayEvent{Tick: rec.Tick, Text: text, Warning: true})
+		case "eliminated":
+			text := fmt.Sprintf("%s eliminated by %s", replay.name(rec.Eliminated), replay.name(rec.By))
+			switch rec.By {
+			case 0:
+				text = replay.name(rec.Eliminated) + " eliminated by the zone"
+			case rec.Eliminated:
+				text = replay.name(rec.Eliminated) + " eliminated itself"
+			}
+			replay.Events = append(replay.Events, replayEvent{Tick: rec.Tick, Text: text})
+		case "radio":
+			text := fmt.Sprintf("%s -> #%s: %s", replay.name(rec.From), rec.Channel, rec.Message)
+			replay.Events = append(replay.Events, replayEvent{Tick: rec.Tick, Text: text})
+		case "result":
+			var result game.Result
+			if err := json.Unmarshal([]byte(line), &result); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			replay.Result = &result
+		default: // state records (older logs have no type)
+			var state game.GameState
+			if err := json.Unmarshal([]byte(line), &state); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if len(state.Players) == 0 {
+				state.Players = []game.Player{state.P1, state.P2}
+			}
+			replay.States = append(replay.States, state)
+		}
+	}
+	if len(replay.States) == 0 {
+		return nil, fmt.Errorf("match log has no state records")
+	}
+	return replay, nil
+}
+
+// name returns the bot name of a 1-based player ID, or "P<id>" when the log has none.
+func (r *replayLog) name(id int) string {
+	if id >= 1 && id <= len(r.Names) && r.Names[id-1] != "" {
+		return r.Names[id-1]
+	}
+	return fmt.Sprintf("P%d", id)
+}
+
+// team returns the 1-based team of a player, or 0 when the match has no teams.
+func (r *replayLog) team(id int) int {
+	if id >= 1 && id <= len(r.Teams) {
+		return r.Teams[id-1]
+	}
+	return 0
+}
+
+// winnerTeam returns the team shared by all winners, or 0 when they are on different sides or there are no teams.
+func (r *replayLog) winnerTeam() int {
+	if r.Result == nil || len(r.Result.Winners) == 0 {
+		return 0
+	}
+	team := r.team(r.Result.Winners[0])
+	for _, id := range r.Result.Winners[1:] {
+		if r.team(id) != team {
+			return 0
+		}
+	}
+	return team
+}
+
+// ANSI colors of players (by team when the match has teams), in the visualizer's palette order.
+var replayColors = []string{"34", "31", "32", "33", "35", "91", "36", "95"}
+
+// headingArrows are the characters for headings 0, 45, ..., 315 degrees, with the grid offset
+// of the cell each one is drawn in next to its player.
+var headingArrows = []struct {
+	char   rune
+	dx, dy int
+}{
+	{'↑', 0, -1}, {'↗', 1, -1}, {'→', 1, 0}, {'↘', 1, 1},
+	{'↓', 0, 1}, {'↙', -1, 1}, {'←', -1, 0}, {'↖', -1, -1},
+}
+
+// replayCell is a character of the field grid with its ANSI color ("" for none).
+type replayCell struct {
+	char  rune
+	color string
+}
+
+// replayView renders the frames of a replay.
+type replayView struct {
+	log    *replayLog
+	cfg    *config.Config
+	cols   int
+	rows   int
+	colors bool
+}
+
+func newReplayView(log *replayLog, cfg *config.Config, cols int, colors bool) *replayView {
+	// Terminal cells are about twice as tall as wide
+	rows := int(math.Round(float64(cols) * float64(cfg.Field.Height) / float64(cfg.Field.Width) / 2))
+	if rows < 5 {
+		rows = 5
+	}
+	return &replayView{log: log, cfg: cfg, cols: cols, rows: rows, colors: colors}
+}
+
+// cell returns the grid cell of field position (x, y) and whether it is on the grid.
+func (v *replayView) cell(x, y float64) (int, int, bool) {
+	w, h := float64(v.cfg.Field.Width), float64(v.cfg.Field.Height)
+	col := int(math.Round((x + w/2) / w * float64(v.cols-1)))
+	row := int(math.Round((h/2 - y) / h * float64(v.rows-1)))
+	return col, row, col >= 0 && col < v.cols && row >= 0 && row < v.rows
+}
+
+// center returns the field position of the center of a grid cell.
+func (v *replayView) center(col, row int) (float64, float64) {
+	w, h := float64(v.cfg.Field.Width), float64(v.cfg.Field.Height)
+	return float64(col)/float64(v.cols-1)*w - w/2, h/2 - float64(row)/float64(v.rows-1)*h
+}
+
+func (v *replayView) playerColor(id int) string {
+	index := id - 1
+	if id <= len(v.log.Teams) && v.log.Teams[id-1] > 0 {
+		index = v.log.Teams[id-1] - 1
+	}
+	return replayColors[index%len(replayColors)]
+}
+
+func (v *replayView) paint(text, color string) string {
+	if !v.colors || color == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// field draws the grid of a state: zone, obstacles, piles, snowballs, then players on top.
+func (v *replayView) field(state game.GameState) [][]replayCell {
+	grid := make([][]replayCell, v.rows)
+	for row := range grid {
+		grid[row] = make([]replayCell, v.cols)
+		for col := range grid[row] {
+			grid[row][col] = replayCell{char: ' '}
+			x, y := v.center(col, row)
+			if state.Zone != nil && !state.Zone.Contains(x, y) {
+				grid[row][col] = replayCell{'.', "31"}
+			}
+			for _, o := range v.log.Obstacles {
+				if o.Contains(x, y) {
+					grid[row][col] = replayCell{'#', "90"}
+				}
+			}
+		}
+	}
+	set := func(x, y float64, c replayCell) {
+		if col, row, ok := v.cell(x, y); ok {
+			grid[row][col] = c
+		}
+	}
+
+	for _, pile := range state.Piles {
+		set(pile.X, pile.Y, replayCell{'*', "1"})
+	}
+	for _, sb := range state.Snowballs {
+		set(sb.X, sb.Y, replayCell{'o', "1;37"})
+	}
+	for i, p := range state.Players {
+		if !p.Alive() {
+			continue
+		}
+		arrow := headingArrows[int(math.Round(p.Angle/45))%len(headingArrows)]
+		if col, row, ok := v.cell(p.X, p.Y); ok {
+			col, row = col+arrow.dx, row+arrow.dy
+			if col >= 0 && col < v.cols && row >= 0 && row < v.rows {
+				grid[row][col] = replayCell{arrow.char, v.playerColor(i + 1)}
+			}
+		}
+	}
+	// Players last so arrows never hide them; eliminated ones below the living
+	for pass := 0; pass < 2; pass++ {
+		for i, p := range state.Players {
+			if p.Alive() != (pass == 1) {
+				continue
+			}
+			char := 'x'
+			color := "90"
+			if p.Alive() {
+				char = rune('0' + (i+1)%10)
+				color = "1;" + v.playerColor(i+1)
+			}
+			set(p.X, p.Y, replayCell{char, color})
+		}
+	}
+	return grid
+}
+
+// panel returns the side panel lines: tick, one line per player and the latest events.
+func (v *replayView) panel(index int, status string) []string {
+	state := v.log.States[index]
+	last := v.log.States[len(v.log.States)-1].Tick
+	lines := []string{fmt.Sprintf("Tick %d / %d  %s", state.Tick, last, status)}
+	if state.Zone != nil {
+		lines = append(lines, fmt.Sprintf("Zone radius %.0f", state.Zone.Radius))
+	}
+	lines = append(lines, "")
+
+	const barWidth = 10
+	maxHP := v.cfg.Snowbot.MaxHP
+	for i, p := range state.Players {
+		filled := 0
+		if maxHP > 0 {
+			filled = int(math.Ceil(float64(max(p.HP, 0)) * barWidth / float64(maxHP)))
+		}
+		filled = min(filled, barWidth)
+		bar := v.paint(strings.Repeat("█", filled), "32") + v.paint(strings.Repeat("░", barWidth-filled), "31")
+		arrow := headingArrows[int(math.Round(p.Angle/45))%len(headingArrows)].char
+		name := fmt.Sprintf("%d %-14.14s", (i+1)%10, v.log.name(i+1))
+		line := fmt.Sprintf("%s %s %3d  %c %3.0f°  snowballs %d", v.paint(name, v.playerColor(i+1)), bar, p.HP, arrow, p.Angle, p.SnowballCount)
+		if !p.Alive() {
+			line = v.paint(name, "90") + " eliminated"
+			if p.EliminatedAt > 0 {
+				line += fmt.Sprintf(" at tick %d", p.EliminatedAt)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	if index == len(v.log.States)-1 && v.log.Result != nil {
+		lines = append(lines, "", v.resultMessage())
+	}
+
+	// Fill the rest of the grid height with the latest events up to this tick
+	lines = append(lines, "", "Log:")
+	var events []replayEvent
+	for _, e := range v.log.Events {
+		if e.Tick <= state.Tick {
+			events = append(events, e)
+		}
+	}
+	room := max(v.rows+2-len(lines), 3)
+	if len(events) > room {
+		events = events[len(events)-room:]
+	}
+	for _, e := range events {
+		color := ""
+		if e.Warning {
+			color = "33"
+		}
+		lines = append(lines, v.paint(fmt.Sprintf("%4d %s", e.Tick, e.Text), color))
+	}
+	return lines
+}
+
+// resultMessage describes the outcome from the result record, like the HTML visualizer.
+func (v *replayView) resultMessage() string {
+	result := v.log.Result
+	switch {
+	case result.Reason == game.EndError:
+		return "Match aborted (error)"
+	case result.Reason == game.EndAllDead:
+		return "All players eliminated"
+	case v.log.winnerTeam() > 0 && result.Reason == game.EndMaxTicks:
+		return fmt.Sprintf("Team %d wins (Time up)", v.log.winnerTeam())
+	case v.log.winnerTeam() > 0:
+		return fmt.Sprintf("Team %d wins", v.log.winnerTeam())
+	case len(result.Winners) == 1 && result.Reason == game.EndMaxTicks:
+		return v.log.name(result.Winners[0]) + " wins (Time up)"
+	case len(result.Winners) == 1:
+		return v.log.name(result.Winners[0]) + " wins"
+	}
+	return "Draw - Equal HP"
+}
+
+// frame renders the grid (with a border) and the side panel next to it.
+func (v *replayView) frame(index int, status string) []string {
+	grid := v.field(v.log.States[index])
+	panel := v.panel(index, status)
+
+	border := "+" + strings.Repeat("-", v.cols) + "+"
+	lines := []string{border}
+	for _, row := range grid {
+		var b strings.Builder
+		b.WriteString("|")
+		for _, c := range row {
+			b.WriteString(v.paint(string(c.char), c.color))
+		}
+		b.WriteString("|")
+		lines = append(lines, b.String())
+	}
+	lines = append(lines, border)
+
+	for i := range lines {
+		if i < len(panel) {
+			lines[i] += "  " + panel[i]
+		}
+	}
+	for i := len(lines); i < len(panel); i++ {
+		lines = append(lines, strings.Repeat(" ", v.cols+4)+panel[i])
+	}
+	return lines
+}
+
+// print writes every every-th frame from start, and the last one, one after another.
+func (v *replayView) print(w io.Writer, start, every int) error {
+	last := len(v.log.States) - 1
+	for i := start; i <= last; i++ {
+		if v.log.States[i].Tick%every != 0 && i != start && i != last {
+			continue
+		}
+		for _, line := range v.frame(i, "") {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// play runs the interactive viewer until the user quits. Keys are read from the terminal
+// (/dev/tty), so a log piped on stdin can still be controlled.
+func (v *replayView) play(start, fps int, logFromStdin bool) error {
+	keyboard, err := os.Open("/dev/tty")
+	if err != nil {
+		if logFromStdin {
+			return fmt.Errorf("no terminal for keyboard input (use --print): %w", err)
+		}
+		keyboard = os.Stdin
+	} else {
+		defer keyboard.Close()
+	}
+	// Read keys without waiting for Enter. Without stty, keys work followed by Enter.
+	if restore := cbreak(keyboard); restore != nil {
+		defer restore()
+	}
+
+	out := os.Stdout
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go readKeys(keyboard, keys)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	index, playing, speed := start, false, fps
+	ticker := time.NewTicker(time.Second / time.Duration(speed))
+	defer ticker.Stop()
+	last := len(v.log.States) - 1
+	for {
+		status := "paused"
+		if playing {
+			status = fmt.Sprintf("playing %d/s", speed)
+		}
+		var b strings.Builder
+		b.WriteString("\x1b[H")
+		for _, line := range v.frame(index, status) {
+			b.WriteString(line + "\x1b[K\n")
+		}
+		b.WriteString("space play/pause  ←/→ step  ↑/↓ ±50  home/end  +/- speed  q quit\x1b[K\x1b[J")
+		fmt.Fprint(out, b.String())
+
+		select {
+		case <-interrupts:
+			return nil
+		case <-ticker.C:
+			if !playing {
+				continue
+			}
+			if index < last {
+				index++
+			}
+			if index == last {
+				playing = false
+			}
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch key {
+			case "q", "Q", "esc":
+				return nil
+			case " ":
+				playing = !playing
+				if playing && index == last {
+					index = 0
+				}
+			case "right", "l":
+				playing, index = false, min(index+1, last)
+			case "left", "h":
+				playing, index = false, max(index-1, 0)
+			case "up", "k":
+				index = min(index+50, last)
+			case "down", "j":
+				index = max(index-50, 0)
+			case "home", "g":
+				index = 0
+			case "end", "G":
+				index = last
+			case "+", "=":
+				speed = min(speed*2, 1000)
+				ticker.Reset(time.Second / time.Duration(speed))
+			case "-", "_":
+				speed = max(speed/2, 1)
+				ticker.Reset(time.Second / time.Duration(speed))
+			}
+		}
+	}
+}
+
+// cbreak switches the terminal to unbuffered input without echo and returns a function that
+// restores it, or nil when the terminal cannot be configured with stty.
+func cbreak(tty *os.File) func() {
+	stty := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		return cmd.Output()
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil
+	}
+	return func() {
+		stty(strings.TrimSpace(string(saved)))
+	}
+}
+
+// readKeys sends the keys read from r to keys, naming the arrow, home, end and escape keys.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		seq := string(buf[:n])
+		switch seq {
+		case "\x1b":
+			keys <- "esc"
+			continue
+		case "\x1b[A", "\x1bOA":
+			keys <- "up"
+			continue
+		case "\x1b[B", "\x1bOB":
+			keys <- "down"
+			continue
+		case "\x1b[C", "\x1bOC":
+			keys <- "right"
+			continue
+		case "\x1b[D", "\x1bOD":
+			keys <- "left"
+			continue
+		case "\x1b[H", "\x1bOH", "\x1b[1~", "\x1b[7~":
+			keys <- "home"
+			continue
+		case "\x1b[F", "\x1bOF", "\x1b[4~", "\x1b[8~":
+			keys <- "end"
+			continue
+		}
+		if strings.HasPrefix(seq, "\x1b") {
+			continue // other escape sequences
+		}
+		// Plain keys, possibly several at once (or a line in line-buffered mode)
+		for _, r := range seq {
+			if r != '\n' && r != '\r' {
+				keys <- string(r)
+			}
+		}
+	}
+}
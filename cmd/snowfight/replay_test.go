@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"strings"
+	"testing"
+)
+
+// replayTestLog is a small two-player match: P2 is hit out at tick 2.
+const replayTestLog = `{"type":"meta","botNames":["alpha","beta"],"obstacles":[{"shape":"rect","x":0,"y":0,"width":10,"height":10}]}
+{"type":"state","tick":1,"players":[{"x":-40,"y":0,"hp":100,"angle":90,"snowball_count":10},{"x":40,"y":0,"hp":10,"angle":270,"snowball_count":10}],"snowballs":[{"x":-30,"y":0}]}
+{"type":"warning","tick":1,"warnedPlayer":1,"api":"move","warning":"not enough energy"}
+{"type":"radio","tick":1,"from":2,"channel":"team","message":{"x":1}}
+{"type":"state","tick":2,"players":[{"x":-40,"y":0,"hp":100,"angle":90,"snowball_count":9},{"x":40,"y":0,"hp":0,"angle":270,"snowball_count":10,"eliminated_at":2}],"snowballs":[]}
+{"type":"eliminated","tick":2,"player":2,"by":1}
+{"type":"result","winners":[1],"reason":"last_bot_standing","tick":2}
+`
+
+func replayTestConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Field.Width, cfg.Field.Height = 100, 100
+	cfg.Snowbot.MaxHP = 100
+	return cfg
+}
+
+func TestParseReplayLog(t *testing.T) {
+	replay, err := parseReplayLog(replayTestLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Names) != 2 || replay.name(1) != "alpha" || replay.name(3) != "P3" {
+		t.Errorf("unexpected names %v", replay.Names)
+	}
+	if len(replay.Obstacles) != 1 || replay.Obstacles[0].Width != 10 {
+		t.Errorf("unexpected obstacles %+v", replay.Obstacles)
+	}
+	if len(replay.States) != 2 || replay.States[1].Tick != 2 || replay.States[1].Players[1].EliminatedAt != 2 {
+		t.Errorf("unexpected states %+v", replay.States)
+	}
+	want := []replayEvent{
+		{Tick: 1, Text: "alpha: move - not enough energy", Warning: true},
+		{Tick: 1, Text: `beta -> #team: {"x":1}`},
+		{Tick: 2, Text: "beta eliminated by alpha"},
+	}
+	if len(replay.Events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), replay.Events)
+	}
+	for i := range want {
+		if replay.Events[i] != want[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, want[i], replay.Events[i])
+		}
+	}
+	if replay.Result == nil || replay.Result.Reason != game.EndLastBotStanding || len(replay.Result.Winners) != 1 {
+		t.Errorf("unexpected result %+v", replay.Result)
+	}
+
+	// Older logs have untyped two-player state records
+	old, err := parseReplayLog(`{"tick":1,"p1":{"x":1,"hp":5},"p2":{"x":2,"hp":6},"snowballs":[]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old.States) != 1 || len(old.States[0].Players) != 2 || old.States[0].Players[1].HP != 6 {
+		t.Errorf("expected the players from p1/p2, got %+v", old.States)
+	}
+
+	if _, err := parseReplayLog(`{"type":"meta"}`); err == nil {
+		t.Error("expected an error for a log without state records")
+	}
+	if _, err := parseReplayLog("{\"tick\":1}\nnot json"); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestReplayView_ResultMessage(t *testing.T) {
+	cases := []struct {
+		result game.Result
+		teams  []int
+		want   string
+	}{
+		{game.Result{Winners: []int{1}, Reason: game.EndLastBotStanding}, nil, "alpha wins"},
+		{game.Result{Winners: []int{2}, Reason: game.EndMaxTicks}, nil, "beta wins (Time up)"},
+		{game.Result{Winners: []int{1, 2}, Reason: game.EndMaxTicks}, nil, "Draw - Equal HP"},
+		{game.Result{Reason: game.EndAllDead}, nil, "All players eliminated"},
+		{game.Result{Reason: game.EndError}, nil, "Match aborted (error)"},
+		{game.Result{Winners: []int{1, 3}, Reason: game.EndLastTeamStanding}, []int{2, 1, 2}, "Team 2 wins"},
+		{game.Result{Winners: []int{2}, Reason: game.EndMaxTicks}, []int{2, 1, 2}, "Team 1 wins (Time up)"},
+		{game.Result{Winners: []int{1, 2}, Reason: game.EndMaxTicks}, []int{2, 1, 2}, "Draw - Equal HP"},
+	}
+	for _, tc := range cases {
+		result := tc.result
+		log := &replayLog{Names: []string{"alpha", "beta"}, Teams: tc.teams, States: []game.GameState{{}}, Result: &result}
+		view := newReplayView(log, replayTestConfig(), 20, false)
+		if got := view.resultMessage(); got != tc.want {
+			t.Errorf("winners %v (%s), teams %v: expected %q, got %q", tc.result.Winners, tc.result.Reason, tc.teams, tc.want, got)
+		}
+	}
+}
+
+func TestReplayView_PrintFrame(t *testing.T) {
+	replay, err := parseReplayLog(replayTestLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := newReplayView(replay, replayTestConfig(), 21, false)
+
+	// Only the last frame, which shows the result
+	var buf bytes.Buffer
+	if err := view.print(&buf, 1, 50); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"+---------------------+  Tick 2 / 2  ",
+		"|                     |  ",
+		"|                     |  1 alpha          ██████████ 100  →  90°  snowballs 9",
+		"|                     |  2 beta           eliminated at tick 2",
+		"|                     |  ",
+		"|                     |  alpha wins",
+		"|  1→     ##       x  |  ",
+		"|                     |  Log:",
+		"|                     |     1 alpha: move - not enough energy",
+		"|                     |     1 beta -> #team: {\"x\":1}",
+		"|                     |     2 beta eliminated by alpha",
+		"|                     |",
+		"+---------------------+",
+		"",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected frame:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// botStoragePath returns the file holding the stored value of a bot in the state directory.
+// Bot names drop the owner of a URL (alice/snowbot and bob/snowbot are both "snowbot/bot"),
+// so the name is only kept for readability and a hash of the full URL tells bots apart:
+// "repo/bot" is stored as "repo_bot-<hash>.json".
+func botStoragePath(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := strings.ReplaceAll(extractBotName(url), "/", "_")
+	return filepath.Join(dir, name+"-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// loadBotStorage reads the stored value of each bot from the state directory, keyed by bot URL.
+// Bots without a file start with no stored value.
+func loadBotStorage(dir string, botURLs []string) (map[string]json.RawMessage, error) {
+	storage := make(map[string]json.RawMessage, len(botURLs))
+	for _, url := range botURLs {
+		name := extractBotName(url)
+		data, err := os.ReadFile(botStoragePath(dir, url))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading storage of %s: %w", name, err)
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("storage of %s is not valid JSON", name)
+		}
+		storage[url] = data
+	}
+	return storage, nil
+}
+
+// saveBotStorage writes the stored value of each bot to the state directory.
+// A bot that cleared its storage has its file removed.
+func saveBotStorage(dir string, storage map[string]json.RawMessage) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	for url, data := range storage {
+		path := botStoragePath(dir, url)
+		if data == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing storage of %s: %w", extractBotName(url), err)
+			}
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("writing storage of %s: %w", extractBotName(url), err)
+		}
+	}
+	return nil
+}
+
+// storageSnapshot returns the stored values of the given bots, in order, for one match.
+// Every match of a league round starts from the same snapshot, so results do not depend
+// on the order in which parallel matches finish.
+func storageSnapshot(storage map[string]json.RawMessage, botURLs []string) []json.RawMessage {
+	snapshot := make([]json.RawMessage, len(botURLs))
+	for i, url := range botURLs {
+		snapshot[i] = storage[url]
+	}
+	return snapshot
+}
+
+// updateBotStorage records the values the bots of one match left in storage.
+func updateBotStorage(storage map[string]json.RawMessage, botURLs []string, values []json.RawMessage) {
+	for i, url := range botURLs {
+		if i < len(values) {
+			storage[url] = values[i]
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBotStorage_OwnersDoNotCollide(t *testing.T) {
+	alice := "https://raw.githubusercontent.com/alice/snowbot/main/bot.js"
+	bob := "https://raw.githubusercontent.com/bob/snowbot/main/bot.js"
+	if extractBotName(alice) != extractBotName(bob) {
+		t.Fatalf("expected both bots to share the name %q", extractBotName(alice))
+	}
+
+	dir := t.TempDir()
+	if botStoragePath(dir, alice) == botStoragePath(dir, bob) {
+		t.Fatalf("expected different storage files, both are %s", botStoragePath(dir, alice))
+	}
+
+	storage := map[string]json.RawMessage{}
+	updateBotStorage(storage, []string{alice, bob}, []json.RawMessage{json.RawMessage(`"a"`), json.RawMessage(`"b"`)})
+	if err := saveBotStorage(dir, storage); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadBotStorage(dir, []string{bob, alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := storageSnapshot(loaded, []string{bob, alice})
+	if string(snapshot[0]) != `"b"` || string(snapshot[1]) != `"a"` {
+		t.Errorf("expected each bot to get its own value back, got %s and %s", snapshot[0], snapshot[1])
+	}
+
+	// A bot that cleared its storage loses its file
+	storage[alice] = nil
+	if err := saveBotStorage(dir, storage); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = loadBotStorage(dir, []string{alice, bob}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded[alice]; ok || string(loaded[bob]) != `"b"` {
+		t.Errorf("expected only bob's value to remain, got %v", loaded)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"snowfight/internal/config"
+	"sort"
+	"strings"
+)
+
+func showVerifyHelp() {
+	fmt.Println("Usage: snowfight verify <match-log-file> <js-file-1> <js-file-2> ... <js-file-N>")
+	fmt.Println()
+	fmt.Println("Verify that a match log was produced by the given bots and config.toml.")
+	fmt.Println()
+	fmt.Println("The bot sources and config are checked against the hashes in the log's meta record,")
+	fmt.Println("then the match is re-simulated with the recorded seed and compared tick by tick.")
+	fmt.Println()
+	fmt.Println("Arguments:")
+	fmt.Println("  <match-log-file>   JSONL file from 'snowfight match' output")
+	fmt.Println("  <js-file>          Path or URL to each bot, in the original player order")
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Println("  snowfight match bot1.js bot2.js > match.jsonl")
+	fmt.Println("  snowfight verify match.jsonl bot1.js bot2.js")
+}
+
+// matchMeta is the meta record written at the start of a match log.
+type matchMeta struct {
+	BotNames   []string          `json:"botNames"`
+	BotHashes  []string          `json:"botHashes"`
+	Seed       int64             `json:"seed"`
+	ConfigHash string            `json:"configHash"`
+	Teams      []int             `json:"teams"`
+	Groups     []string          `json:"groups"`
+	Storage    []json.RawMessage `json:"storage"` // Stored values at the start of a league match
+}
+
+// matchLog holds the records of a match log needed for verification.
+type matchLog struct {
+	Meta   *matchMeta
+	States []map[string]interface{}
+	// Records are the state, warning, radio and eliminated records in log order, which is tick
+	// order. Wall-clock timeout warnings are left out because they are not reproducible.
+	Records []map[string]interface{}
+	Result  map[string]interface{}
+}
+
+func runVerify(args []string) error {
+	// Check for help flags
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		showVerifyHelp()
+		return nil
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("usage: snowfight verify <match-log-file> <js-file-1> ... <js-file-N>")
+	}
+
+	logContent, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	recorded, err := parseMatchLog(logContent)
+	if err != nil {
+		return err
+	}
+	meta := recorded.Meta
+	if meta == nil || meta.Seed == 0 || meta.ConfigHash == "" || len(meta.BotHashes) == 0 {
+		return fmt.Errorf("log has no seed/config/bot hashes in its meta record; it cannot be verified")
+	}
+
+	bots := args[1:]
+	if len(bots) != len(meta.BotHashes) {
+		return fmt.Errorf("log has %d bots, got %d", len(meta.BotHashes), len(bots))
+	}
+	for i, file := range bots {
+		_, hash, err := readBot(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if hash != meta.BotHashes[i] {
+			return fmt.Errorf("player %d: source hash of %s does not match the log (%s != %s)", i+1, file, hash, meta.BotHashes[i])
+		}
+	}
+
+	cfg, err := config.Load("config.toml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	cfg.Match.RandomSeed = meta.Seed
+	if hash := cfg.Hash(); hash != meta.ConfigHash {
+		return fmt.Errorf("config.toml does not match the log (config hash %s != %s)", hash, meta.ConfigHash)
+	}
+
+	var buf bytes.Buffer
+	simErr := runMatchWithOptions(bots, &buf, MatchOptions{Seed: meta.Seed, Teams: meta.Teams, Groups: meta.Groups, Storage: meta.Storage})
+	if simErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: re-simulation ended with error: %v\n", simErr)
+	}
+	replayed, err := parseMatchLog(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to parse re-simulated match: %w", err)
+	}
+
+	if tick, detail, ok := firstDivergence(recorded, replayed); !ok {
+		fmt.Printf("DIVERGED at tick %d: %s\n", tick, detail)
+		return fmt.Errorf("match log does not match re-simulation (first divergence at tick %d)", tick)
+	}
+
+	fmt.Printf("OK: %d ticks verified (seed %d)\n", len(recorded.States), meta.Seed)
+	return nil
+}
+
+// parseMatchLog reads meta, state, event and result records from JSONL match output.
+func parseMatchLog(content []byte) (*matchLog, error) {
+	log := &matchLog{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch record["type"] {
+		case "meta":
+			var meta matchMeta
+			if err := json.Unmarshal([]byte(line), &meta); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			log.Meta = &meta
+		case "state":
+			log.States = append(log.States, record)
+			log.Records = append(log.Records, record)
+		case "warning":
+			if record["limit"] != "wall_clock" {
+				log.Records = append(log.Records, record)
+			}
+		case "radio", "eliminated":
+			log.Records = append(log.Records, record)
+		case "result":
+			log.Result = record
+		}
+	}
+	return log, nil
+}
+
+// firstDivergence compares two logs record by record in tick order and reports the first difference.
+func firstDivergence(recorded, replayed *matchLog) (tick int, detail string, ok bool) {
+	for i := 0; i < len(recorded.Records) || i < len(replayed.Records); i++ {
+		switch {
+		case i >= len(replayed.Records):
+			if recorded.Records[i]["type"] == "state" {
+				return recordTick(recorded.Records[i]), "log continues after the re-simulated match ended", false
+			}
+			return recordTick(recorded.Records[i]), fmt.Sprintf("log has an extra %v record", recorded.Records[i]["type"]), false
+		case i >= len(recorded.Records):
+			if replayed.Records[i]["type"] == "state" {
+				return recordTick(replayed.Records[i]), "log ends before the re-simulated match", false
+			}
+			return recordTick(replayed.Records[i]), fmt.Sprintf("log is missing a %v record", replayed.Records[i]["type"]), false
+		}
+
+		a, b := recorded.Records[i], replayed.Records[i]
+		if a["type"] != b["type"] {
+			return min(recordTick(a), recordTick(b)), fmt.Sprintf("log has a %v record where the re-simulation has a %v record", a["type"], b["type"]), false
+		}
+		prefix := ""
+		if a["type"] != "state" {
+			prefix = fmt.Sprint(a["type"])
+		}
+		if path, ok := diffValues(prefix, a, b); !ok {
+			return recordTick(a), path, false
+		}
+	}
+
+	lastTick := 0
+	if n := len(recorded.States); n > 0 {
+		lastTick = recordTick(recorded.States[n-1])
+	}
+	if path, ok := diffValues("result", recorded.Result, replayed.Result); !ok {
+		return lastTick, path, false
+	}
+	return 0, "", true
+}
+
+func recordTick(record map[string]interface{}) int {
+	if t, ok := record["tick"].(float64); ok {
+		return int(t)
+	}
+	return 0
+}
+
+// diffValues returns the path and values of the first difference between two decoded JSON values.
+func diffValues(path string, a, b interface{}) (string, bool) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, exists := av[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			if p, ok := diffValues(child, av[k], bv[k]); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if len(av) != len(bv) {
+			return fmt.Sprintf("%s: log has %d entries, re-simulation has %d", path, len(av), len(bv)), false
+		}
+		for i := range av {
+			if p, ok := diffValues(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i]); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	}
+	if reflect.DeepEqual(a, b) {
+		return "", true
+	}
+	return fmt.Sprintf("%s: log=%v re-simulation=%v", path, jsonString(a), jsonString(b)), false
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const verifyTestLog = `{"type":"meta","botNames":["a","b"],"botHashes":["h1","h2"],"seed":7,"configHash":"c"}
+{"type":"state","tick":1,"players":[{"x":0,"y":0,"hp":100},{"x":10,"y":0,"hp":100}]}
+{"type":"warning","tick":2,"warnedPlayer":1,"api":"move","warning":"called multiple times in one tick"}
+{"type":"warning","tick":2,"warnedPlayer":2,"api":"run","warning":"execution timed out","limit":"wall_clock"}
+{"type":"state","tick":2,"players":[{"x":0,"y":0,"hp":100},{"x":10,"y":0,"hp":90}]}
+{"type":"eliminated","tick":3,"player":2,"by":1}
+{"type":"state","tick":3,"players":[{"x":0,"y":0,"hp":100},{"x":10,"y":0,"hp":0}]}
+{"type":"result","winners":[1],"reason":"last_bot_standing","tick":3}
+`
+
+func TestParseMatchLog(t *testing.T) {
+	log, err := parseMatchLog([]byte(verifyTestLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Meta == nil || log.Meta.Seed != 7 || len(log.Meta.BotHashes) != 2 {
+		t.Errorf("unexpected meta %+v", log.Meta)
+	}
+	if len(log.States) != 3 {
+		t.Errorf("expected 3 states, got %d", len(log.States))
+	}
+	// The wall-clock warning is left out
+	var types []string
+	for _, r := range log.Records {
+		types = append(types, r["type"].(string))
+	}
+	if got := strings.Join(types, ","); got != "state,warning,state,eliminated,state" {
+		t.Errorf("unexpected records %s", got)
+	}
+	if log.Result["reason"] != "last_bot_standing" {
+		t.Errorf("unexpected result %+v", log.Result)
+	}
+
+	if _, err := parseMatchLog([]byte("{not json\n")); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+}
+
+func TestFirstDivergence(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(verifyTestLog), "\n")
+	without := func(i int) string {
+		return strings.Join(append(append([]string{}, lines[:i]...), lines[i+1:]...), "\n")
+	}
+
+	for _, tc := range []struct {
+		name     string
+		recorded string
+		tick     int
+		detail   string
+	}{
+		{"identical", verifyTestLog, 0, ""},
+		{"wall-clock warning only in one log", without(3), 0, ""},
+		{"extra record", strings.Replace(verifyTestLog, lines[4], lines[2]+"\n"+lines[4], 1), 2, "log has a warning record where the re-simulation has a state record"},
+		{"missing record", without(5), 3, "log has a state record where the re-simulation has a eliminated record"},
+		{"missing last record", without(6), 3, "log ends before the re-simulated match"},
+		{"changed field", strings.Replace(verifyTestLog, `"hp":90`, `"hp":80`, 1), 2, "players[1].hp: log=80 re-simulation=90"},
+		{"changed result", strings.Replace(verifyTestLog, `"winners":[1]`, `"winners":[2]`, 1), 3, "result.winners[0]: log=2 re-simulation=1"},
+	} {
+		recorded, err := parseMatchLog([]byte(tc.recorded))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		replayed, err := parseMatchLog([]byte(verifyTestLog))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tick, detail, ok := firstDivergence(recorded, replayed)
+		if ok != (tc.detail == "") || tick != tc.tick || detail != tc.detail {
+			t.Errorf("%s: expected tick %d %q, got tick %d %q (ok=%v)", tc.name, tc.tick, tc.detail, tick, detail, ok)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	for _, tc := range []struct {
+		a, b interface{}
+		want string
+	}{
+		{map[string]interface{}{"x": 1.0}, map[string]interface{}{"x": 1.0}, ""},
+		{map[string]interface{}{"x": 1.0}, map[string]interface{}{"x": 2.0}, "x: log=1 re-simulation=2"},
+		{map[string]interface{}{"x": 1.0}, map[string]interface{}{}, "x: log=1 re-simulation=null"},
+		{[]interface{}{1.0}, []interface{}{1.0, 2.0}, ": log has 1 entries, re-simulation has 2"},
+	} {
+		path, ok := diffValues("", tc.a, tc.b)
+		if ok != (tc.want == "") || path != tc.want {
+			t.Errorf("diffValues(%v, %v): expected %q, got %q", tc.a, tc.b, tc.want, path)
+		}
+	}
+}
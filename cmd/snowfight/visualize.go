@@ -257,6 +257,9 @@ let tickDisplay;
 let warningsByTick = {};
 let botNames = {};
 let allWarnings = []; // Flat list for log panel
+let matchResult = null; // Final {"type":"result"} record, if present
+let obstacles = []; // Static obstacles from the meta record
+let teams = []; // Team number per bot from the meta record (empty = no teams)
 let snowbotSprite; // SVG sprite
 
 // Game constants (should match Go config)
@@ -319,12 +322,22 @@ function parseMatchData(lines) {
                     if (!warningsByTick[t]) warningsByTick[t] = [];
                     warningsByTick[t].push(rec);
                     allWarnings.push(rec);
+                } else if (rec.type === 'eliminated' || rec.type === 'radio') {
+                    allWarnings.push(rec); // shown in the log panel alongside warnings
+                } else if (rec.type === 'result') {
+                    matchResult = rec;
                 } else if (rec.type === 'meta') {
                     if (rec.botNames) {
                         for (let i = 0; i < rec.botNames.length; i++) {
                             botNames[i + 1] = rec.botNames[i];
                         }
                     }
+                    if (rec.obstacles) {
+                        obstacles = rec.obstacles;
+                    }
+                    if (rec.teams) {
+                        teams = rec.teams;
+                    }
                 } else { // treat as state by default
                     matchData.push(rec);
                 }
@@ -370,7 +383,7 @@ function renderLogPanel() {
     
     for (let w of visibleWarnings) {
         let li = createElement('li');
-        li.class('log-item warning');
+        li.class(w.type === 'warning' ? 'log-item warning' : 'log-item');
         li.parent(list);
         
         let tickSpan = createSpan('Tick ' + w.tick);
@@ -380,8 +393,23 @@ function renderLogPanel() {
         let br = createElement('br');
         br.parent(li);
 
-        let name = botNames[w.warnedPlayer] || ("P" + w.warnedPlayer);
-        let msgSpan = createSpan(name + ": " + w.api + " - " + w.warning);
+        let msg;
+        if (w.type === 'eliminated') {
+            let name = botNames[w.player] || ("P" + w.player);
+            let by = botNames[w.by] || ("P" + w.by);
+            if (w.by === 0) {
+                msg = name + " eliminated by the zone";
+            } else {
+                msg = w.by === w.player ? name + " eliminated itself" : name + " eliminated by " + by;
+            }
+        } else if (w.type === 'radio') {
+            let name = botNames[w.from] || ("P" + w.from);
+            msg = name + " → #" + w.channel + ": " + JSON.stringify(w.message);
+        } else {
+            let name = botNames[w.warnedPlayer] || ("P" + w.warnedPlayer);
+            msg = name + ": " + w.api + " - " + w.warning;
+        }
+        let msgSpan = createSpan(msg);
         msgSpan.class('msg');
         msgSpan.parent(li);
 
@@ -446,8 +474,20 @@ function draw() {
     rectMode(CENTER);
     rect(0, 0, FIELD_WIDTH, FIELD_HEIGHT);
 
+    for (let o of obstacles) {
+        drawObstacle(o);
+    }
+
     if (matchData.length > 0 && matchData[currentTick]) {
         let state = matchData[currentTick];
+        if (state.zone) {
+            drawZone(state.zone);
+        }
+        if (state.piles) {
+            for (let pile of state.piles) {
+                drawPile(pile);
+            }
+        }
         // Draw Players
         if (state.players && state.players.length > 0) {
             for (let i = 0; i < state.players.length; i++) {
@@ -476,8 +516,9 @@ function drawPlayer(p, colorStr, playerID) {
     push();
     translate(p.x, -p.y); // invert Y so north is up
     
-    // Get color palette index (playerID - 1 to make it 0-indexed)
-    let colorIndex = (playerID - 1) % COLOR_PALETTE.length;
+    // Get color palette index (playerID - 1 to make it 0-indexed); teammates share their team's color
+    let team = teams[playerID - 1] || 0;
+    let colorIndex = (team > 0 ? team - 1 : playerID - 1) % COLOR_PALETTE.length;
     let colorInfo = COLOR_PALETTE[colorIndex];
     
     // Rotate to match player angle
@@ -486,6 +527,9 @@ function drawPlayer(p, colorStr, playerID) {
     // Apply color tint using p5.js tint() function
     // This is more reliable than CSS filters for cross-browser compatibility
     let c = color(colorInfo.primary);
+    if (p.hp <= 0) {
+        c.setAlpha(80); // eliminated
+    }
     tint(c);
 
     
@@ -523,6 +567,64 @@ function drawPlayer(p, colorStr, playerID) {
 }
 
 
+function drawObstacle(o) {
+    push();
+    translate(o.x, -o.y); // invert Y so north is up
+    fill(120, 130, 140);
+    stroke(60);
+    strokeWeight(2);
+    if (o.shape === 'circle') {
+        ellipse(0, 0, o.radius * 2, o.radius * 2);
+    } else {
+        rectMode(CENTER);
+        rect(0, 0, o.width, o.height);
+    }
+    pop();
+}
+
+// drawZone shades the field outside the safe zone and outlines its edge.
+function drawZone(zone) {
+    push();
+    noStroke();
+    fill(220, 60, 60, 50);
+    beginShape();
+    vertex(-FIELD_WIDTH / 2, -FIELD_HEIGHT / 2);
+    vertex(FIELD_WIDTH / 2, -FIELD_HEIGHT / 2);
+    vertex(FIELD_WIDTH / 2, FIELD_HEIGHT / 2);
+    vertex(-FIELD_WIDTH / 2, FIELD_HEIGHT / 2);
+    // The zone is cut out as a hole (wound the opposite way)
+    beginContour();
+    for (let a = 360; a > 0; a -= 5) {
+        vertex(zone.x + zone.radius * cos(radians(a)), -zone.y + zone.radius * sin(radians(a)));
+    }
+    endContour();
+    endShape(CLOSE);
+
+    noFill();
+    stroke(220, 60, 60);
+    strokeWeight(3);
+    ellipse(zone.x, -zone.y, zone.radius * 2, zone.radius * 2); // invert Y so north is up
+    pop();
+}
+
+// drawPile draws a snowball pile as a mound of snowballs with its amount.
+function drawPile(pile) {
+    push();
+    translate(pile.x, -pile.y); // invert Y so north is up
+    fill(235, 245, 255);
+    stroke(90, 120, 160);
+    strokeWeight(1);
+    ellipse(-7, 4, 12, 12);
+    ellipse(7, 4, 12, 12);
+    ellipse(0, -5, 12, 12);
+    noStroke();
+    fill(40, 70, 110);
+    textAlign(CENTER, TOP);
+    textSize(12);
+    text(pile.amount, 0, 12);
+    pop();
+}
+
 function drawSnowball(sb) {
     push();
     translate(sb.x, -sb.y); // invert Y so north is up
@@ -533,7 +635,41 @@ function drawSnowball(sb) {
     pop();
 }
 
+// resultMessage describes the outcome from the match result record.
+function resultMessage(result) {
+    const nameOf = (id) => botNames[id] || ("Player " + id);
+    const winners = result.winners || [];
+    // Team shared by all winners, or 0 when they are on different sides or there are no teams.
+    const winnerTeam = winners.length > 0 && winners.every((id) => (teams[id - 1] || 0) === (teams[winners[0] - 1] || 0))
+        ? (teams[winners[0] - 1] || 0) : 0;
+    switch (result.reason) {
+        case 'error':
+            return 'Match aborted (error)';
+        case 'all_dead':
+            return 'All players eliminated';
+        case 'last_bot_standing':
+            return nameOf(winners[0]) + " wins";
+        case 'last_team_standing':
+            return "Team " + winnerTeam + " wins";
+        default:
+            if (winnerTeam > 0) {
+                return "Team " + winnerTeam + " wins (Time up)";
+            }
+            if (winners.length === 1) {
+                return nameOf(winners[0]) + " wins (Time up)";
+            }
+            return 'Draw - Equal HP';
+    }
+}
+
 function drawEndMessage(state) {
+    if (matchResult) {
+        if (currentTick !== matchData.length - 1) return;
+        drawMessageBox(resultMessage(matchResult));
+        return;
+    }
+
+    // Legacy logs without a result record: infer the outcome from the state.
     const alive = [];
     if (state.players && state.players.length > 0) {
         for (let i = 0; i < state.players.length; i++) {
@@ -591,7 +727,10 @@ function drawEndMessage(state) {
         }
     }
 
+    drawMessageBox(msg);
+}
 
+function drawMessageBox(msg) {
     push();
     fill(0, 0, 0, 160);
     rectMode(CENTER);
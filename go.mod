@@ -6,6 +6,7 @@ require (
 	github.com/BurntSushi/toml v1.5.0
 	github.com/buke/quickjs-go v0.6.6
 	github.com/google/go-github/v55 v55.0.0
+	github.com/tetratelabs/wazero v1.9.0
 	golang.org/x/oauth2 v0.15.0
 )
 
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 
 	"github.com/BurntSushi/toml"
@@ -15,6 +19,11 @@ type Config struct {
 	Snowball SnowballConfig `toml:"snowball"`
 	Runtime  RuntimeConfig  `toml:"runtime"`
 	Sensor   SensorConfig   `toml:"sensor"`
+	Radio    RadioConfig    `toml:"radio"`
+	Storage  StorageConfig  `toml:"storage"`
+	Energy   EnergyConfig   `toml:"energy"`
+	Zone     ZoneConfig     `toml:"zone"`
+	Piles    PilesConfig    `toml:"piles"`
 }
 
 // MatchConfig contains match-related settings.
@@ -23,12 +32,59 @@ type MatchConfig struct {
 	MaxPlayers  int   `toml:"max_players"`
 	// RandomSeed: if non-zero, deterministic RNG for spawn and other random features
 	RandomSeed  int64 `toml:"random_seed"`
+	// FriendlyFire lets snowballs damage teammates in team matches.
+	FriendlyFire bool `toml:"friendly_fire"`
+	// Spawn selects how players are placed at the start: "random", "circle", "mirrored" or "fixed".
+	Spawn string `toml:"spawn"`
+	// MinSpawnDistance is the minimum distance between randomly placed players (random and mirrored).
+	MinSpawnDistance float64 `toml:"min_spawn_distance"`
+	// SpawnPoints are the start positions of players 1, 2, ... in fixed mode.
+	SpawnPoints []SpawnPointConfig `toml:"spawn_points"`
+}
+
+// Spawn layouts.
+const (
+	SpawnRandom   = "random"
+	SpawnCircle   = "circle"
+	SpawnMirrored = "mirrored"
+	SpawnFixed    = "fixed"
+)
+
+// SpawnPointConfig is a fixed start position and heading (degrees, north = 0).
+type SpawnPointConfig struct {
+	X     float64 `toml:"x"`
+	Y     float64 `toml:"y"`
+	Angle float64 `toml:"angle"`
 }
 
 // FieldConfig contains field dimension settings.
 type FieldConfig struct {
-	Width  int `toml:"width"`
-	Height int `toml:"height"`
+	Width     int              `toml:"width"`
+	Height    int              `toml:"height"`
+	Obstacles []ObstacleConfig `toml:"obstacles"`
+	// PositionPrecision is the number of decimal places kept in player and snowball positions.
+	// At 0 players move in whole units and snowball positions are not rounded.
+	PositionPrecision int `toml:"position_precision"`
+}
+
+// MaxPositionPrecision bounds field.position_precision.
+const MaxPositionPrecision = 9
+
+// Obstacle shapes.
+const (
+	ShapeRect   = "rect"
+	ShapeCircle = "circle"
+)
+
+// ObstacleConfig describes a static obstacle that blocks movement, snowballs and scans.
+// X and Y are the field coordinates of its center.
+type ObstacleConfig struct {
+	Shape  string  `toml:"shape"` // "rect" or "circle"
+	X      float64 `toml:"x"`
+	Y      float64 `toml:"y"`
+	Width  float64 `toml:"width"`  // rect only
+	Height float64 `toml:"height"` // rect only
+	Radius float64 `toml:"radius"` // circle only
 }
 
 // SnowbotConfig contains snowbot movement constraints.
@@ -38,6 +94,15 @@ type SnowbotConfig struct {
 	MaxHP             int `toml:"max_hp"`
 	MaxSnowball       int `toml:"max_snowball"`
 	MaxFlyingSnowball int `toml:"max_flying_snowball"`
+	// GatherTicks is how many ticks make_snowball() keeps a SnowBot from moving.
+	GatherTicks int `toml:"gather_ticks"`
+	// GatherAmount is how many snowballs are added when gathering completes (0 disables gathering).
+	GatherAmount int `toml:"gather_amount"`
+	// MaxTurnPerTick limits the degrees a turn() can rotate per tick (0 = unlimited).
+	MaxTurnPerTick int `toml:"max_turn_per_tick"`
+	// Acceleration enables inertia: move() sets the desired speed and the actual speed changes
+	// by at most this much per tick (0 = moves are applied instantly).
+	Acceleration int `toml:"acceleration"`
 }
 
 // SnowballConfig contains snowball flight and damage parameters.
@@ -46,32 +111,113 @@ type SnowballConfig struct {
 	Speed             int `toml:"speed"`
 	DamageRadius      int `toml:"damage_radius"`
 	Damage            int `toml:"damage"`
+	// HitMode selects how snowballs hit: "landing" (damage where they land) or "path" (first bot along the way).
+	HitMode string `toml:"hit_mode"`
+	// OwnerImmunity is the distance a snowball travels before it can hit its thrower in path mode.
+	OwnerImmunity int `toml:"owner_immunity"`
+	// TossOrigin selects where toss() throws from: "post_move" (after this tick's turn and move)
+	// or "pre_move" (the position and heading at the start of the tick).
+	TossOrigin string `toml:"toss_origin"`
 }
 
+// Snowball hit modes.
+const (
+	HitModeLanding = "landing"
+	HitModePath    = "path"
+)
+
+// Snowball toss origins.
+const (
+	TossOriginPostMove = "post_move"
+	TossOriginPreMove  = "pre_move"
+)
+
 // RuntimeConfig contains JavaScript runtime resource constraints.
 type RuntimeConfig struct {
 	MaxMemoryBytes int `toml:"max_memory_bytes"`
 	MaxStackBytes  int `toml:"max_stack_bytes"`
-	TickTimeoutMs  int `toml:"tick_timeout_ms"`
+	// WasmMaxMemoryBytes caps the linear memory of WebAssembly bots, which compilers size for
+	// their stack and heap up front (0 disables it).
+	WasmMaxMemoryBytes int `toml:"wasm_max_memory_bytes"`
+	// MaxInstructionsPerTick is the deterministic per-tick execution budget (0 disables it).
+	MaxInstructionsPerTick int `toml:"max_instructions_per_tick"`
+	// TickTimeoutMs is a wall-clock safety backstop; it is not reproducible across machines.
+	TickTimeoutMs int `toml:"tick_timeout_ms"`
 }
 
 // SensorConfig contains sensor-related settings.
 type SensorConfig struct {
 	MinScan int `toml:"min_scan"`
 	MaxScan int `toml:"max_scan"`
+	// ScanSnowballs enables the scan_snowballs API for detecting flying snowballs.
+	ScanSnowballs bool `toml:"scan_snowballs"`
+	// Detail selects what scan reports about snowbots: "basic" or "extended" (also ID, HP, heading, reloading).
+	Detail string `toml:"detail"`
+}
+
+// Sensor detail tiers.
+const (
+	SensorBasic    = "basic"
+	SensorExtended = "extended"
+)
+
+// RadioConfig contains limits for the send/receive team radio.
+type RadioConfig struct {
+	MaxMessageBytes int `toml:"max_message_bytes"` // Limit per message (channel + JSON message)
+	MaxBytesPerTick int `toml:"max_bytes_per_tick"` // Limit per bot and tick (0 disables the radio)
+}
+
+// StorageConfig contains limits for the storage.get/storage.set persistent memory.
+type StorageConfig struct {
+	MaxBytes int `toml:"max_bytes"` // Limit of the stored JSON value (0 disables storage.set)
+}
+
+// EnergyConfig contains the optional energy economy: actions spend energy that regenerates every tick.
+type EnergyConfig struct {
+	Enabled      bool    `toml:"enabled"`
+	MaxEnergy    float64 `toml:"max_energy"`     // Energy pool, full at spawn
+	RegenPerTick float64 `toml:"regen_per_tick"` // Energy regained at the end of every tick
+	MoveCost     float64 `toml:"move_cost"`      // Per distance unit moved
+	TurnCost     float64 `toml:"turn_cost"`      // Per degree turned
+	TossCost     float64 `toml:"toss_cost"`      // Per snowball thrown
+	ScanCost     float64 `toml:"scan_cost"`      // Per scan or scan_snowballs call
+}
+
+// ZoneConfig contains the optional shrinking safe zone: a circle around the field center that
+// starts covering the whole field and shrinks over time, damaging bots outside it.
+type ZoneConfig struct {
+	Enabled        bool    `toml:"enabled"`
+	StartTick      int     `toml:"start_tick"`      // Tick the zone starts shrinking
+	ShrinkPerTick  float64 `toml:"shrink_per_tick"` // Radius lost per tick
+	FinalRadius    float64 `toml:"final_radius"`    // Radius the zone stops shrinking at
+	Damage         int     `toml:"damage"`          // HP lost by each bot outside the zone
+	DamageInterval int     `toml:"damage_interval"` // Ticks between two damage rounds
+}
+
+// PilesConfig contains the optional snowball piles: pickups that appear at random positions
+// and refill the snowballs of bots that come close.
+type PilesConfig struct {
+	Enabled      bool    `toml:"enabled"`
+	Interval     int     `toml:"interval"`      // Ticks between two pile spawns
+	Amount       int     `toml:"amount"`        // Snowballs in a new pile
+	PickupRadius float64 `toml:"pickup_radius"` // Distance within which a bot collects a pile
+	MaxPiles     int     `toml:"max_piles"`     // Piles on the field at once (no spawn while full)
 }
 
 // Default returns the default configuration.
 func Default() *Config {
 	return &Config{
 		Match: MatchConfig{
-			MaxTicks:   1000,
-			MaxPlayers: 2,
-			RandomSeed: 0,
+			MaxTicks:         1000,
+			MaxPlayers:       2,
+			RandomSeed:       0,
+			Spawn:            SpawnRandom,
+			MinSpawnDistance: 200,
 		},
 		Field: FieldConfig{
-			Width:  1000,
-			Height: 1000,
+			Width:             1000,
+			Height:            1000,
+			PositionPrecision: 0,
 		},
 		Snowbot: SnowbotConfig{
 			MinMove:           1,
@@ -79,21 +225,63 @@ func Default() *Config {
 			MaxHP:             100,
 			MaxSnowball:       10,
 			MaxFlyingSnowball: 3,
+			GatherTicks:       5,
+			GatherAmount:      5,
+			MaxTurnPerTick:    0,
+			Acceleration:      0,
 		},
 		Snowball: SnowballConfig{
 			MaxFlyingDistance: 100,
 			Speed:             10,
 			DamageRadius:      5,
 			Damage:            10,
+			HitMode:           HitModeLanding,
+			OwnerImmunity:     20,
+			TossOrigin:        TossOriginPostMove,
 		},
 		Runtime: RuntimeConfig{
-			MaxMemoryBytes: 10485760, // 10MB
-			MaxStackBytes:  1048576,  // 1MB
-			TickTimeoutMs:  100,      // 100ms
+			MaxMemoryBytes:         10485760, // 10MB
+			MaxStackBytes:          1048576,  // 1MB
+			WasmMaxMemoryBytes:     16777216, // 16MB
+			MaxInstructionsPerTick: 1000000,  // 1M instructions
+			TickTimeoutMs:          100,      // 100ms
 		},
 		Sensor: SensorConfig{
-			MinScan: 10,
-			MaxScan: 45,
+			MinScan:       10,
+			MaxScan:       45,
+			ScanSnowballs: true,
+			Detail:        SensorBasic,
+		},
+		Radio: RadioConfig{
+			MaxMessageBytes: 256,
+			MaxBytesPerTick: 1024,
+		},
+		Storage: StorageConfig{
+			MaxBytes: 4096,
+		},
+		Energy: EnergyConfig{
+			Enabled:      false,
+			MaxEnergy:    100,
+			RegenPerTick: 10,
+			MoveCost:     1,
+			TurnCost:     0.1,
+			TossCost:     20,
+			ScanCost:     2,
+		},
+		Zone: ZoneConfig{
+			Enabled:        false,
+			StartTick:      300,
+			ShrinkPerTick:  1,
+			FinalRadius:    100,
+			Damage:         5,
+			DamageInterval: 10,
+		},
+		Piles: PilesConfig{
+			Enabled:      false,
+			Interval:     50,
+			Amount:       5,
+			PickupRadius: 20,
+			MaxPiles:     5,
 		},
 	}
 }
@@ -113,5 +301,87 @@ func Load(path string) (*Config, error) {
 		return cfg, fmt.Errorf("failed to parse config file, using defaults: %w", err)
 	}
 
+	if cfg.Snowball.HitMode != HitModeLanding && cfg.Snowball.HitMode != HitModePath {
+		return Default(), fmt.Errorf("invalid snowball.hit_mode %q (want landing or path), using defaults", cfg.Snowball.HitMode)
+	}
+	if cfg.Snowball.TossOrigin != TossOriginPostMove && cfg.Snowball.TossOrigin != TossOriginPreMove {
+		return Default(), fmt.Errorf("invalid snowball.toss_origin %q (want post_move or pre_move), using defaults", cfg.Snowball.TossOrigin)
+	}
+	if cfg.Field.PositionPrecision < 0 || cfg.Field.PositionPrecision > MaxPositionPrecision {
+		return Default(), fmt.Errorf("invalid field.position_precision %d (want 0 to %d), using defaults", cfg.Field.PositionPrecision, MaxPositionPrecision)
+	}
+	if cfg.Sensor.Detail != SensorBasic && cfg.Sensor.Detail != SensorExtended {
+		return Default(), fmt.Errorf("invalid sensor.detail %q (want basic or extended), using defaults", cfg.Sensor.Detail)
+	}
+
+	for i, o := range cfg.Field.Obstacles {
+		if err := o.validate(); err != nil {
+			return Default(), fmt.Errorf("invalid field.obstacles[%d], using defaults: %w", i, err)
+		}
+	}
+
+	switch cfg.Match.Spawn {
+	case SpawnRandom, SpawnCircle, SpawnMirrored:
+	case SpawnFixed:
+		if len(cfg.Match.SpawnPoints) == 0 {
+			return Default(), fmt.Errorf("match.spawn = %q needs match.spawn_points, using defaults", SpawnFixed)
+		}
+	default:
+		return Default(), fmt.Errorf("invalid match.spawn %q (want random, circle, mirrored or fixed), using defaults", cfg.Match.Spawn)
+	}
+	if z := cfg.Zone; z.ShrinkPerTick < 0 || z.FinalRadius < 0 || z.Damage < 0 || z.DamageInterval < 1 {
+		return Default(), fmt.Errorf("invalid zone (want shrink_per_tick, final_radius and damage of 0 or more, damage_interval of 1 or more), using defaults")
+	}
+	if p := cfg.Piles; p.Interval < 1 || p.Amount < 1 || p.PickupRadius < 0 || p.MaxPiles < 0 {
+		return Default(), fmt.Errorf("invalid piles (want interval and amount of 1 or more, pickup_radius and max_piles of 0 or more), using defaults")
+	}
+	if cfg.Match.MinSpawnDistance < 0 {
+		return Default(), fmt.Errorf("invalid match.min_spawn_distance %v (want 0 or more), using defaults", cfg.Match.MinSpawnDistance)
+	}
+	for i, sp := range cfg.Match.SpawnPoints {
+		if math.Abs(sp.X) > float64(cfg.Field.Width)/2 || math.Abs(sp.Y) > float64(cfg.Field.Height)/2 {
+			return Default(), fmt.Errorf("invalid match.spawn_points[%d] (%v, %v): outside the field, using defaults", i, sp.X, sp.Y)
+		}
+		for j, o := range cfg.Field.Obstacles {
+			if o.contains(sp.X, sp.Y) {
+				return Default(), fmt.Errorf("invalid match.spawn_points[%d] (%v, %v): inside field.obstacles[%d], using defaults", i, sp.X, sp.Y, j)
+			}
+		}
+	}
+
 	return cfg, nil
 }
+
+func (o ObstacleConfig) validate() error {
+	switch o.Shape {
+	case ShapeRect:
+		if o.Width <= 0 || o.Height <= 0 {
+			return fmt.Errorf("rect needs positive width and height")
+		}
+	case ShapeCircle:
+		if o.Radius <= 0 {
+			return fmt.Errorf("circle needs a positive radius")
+		}
+	default:
+		return fmt.Errorf("unknown shape %q (want rect or circle)", o.Shape)
+	}
+	return nil
+}
+
+// contains reports whether (x, y) lies inside the obstacle (boundary included).
+func (o ObstacleConfig) contains(x, y float64) bool {
+	switch o.Shape {
+	case ShapeRect:
+		return math.Abs(x-o.X) <= o.Width/2 && math.Abs(y-o.Y) <= o.Height/2
+	case ShapeCircle:
+		return math.Hypot(x-o.X, y-o.Y) <= o.Radius
+	}
+	return false
+}
+
+// Hash returns a SHA-256 fingerprint of the effective configuration.
+func (c *Config) Hash() string {
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
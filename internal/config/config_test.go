@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,6 +13,18 @@ func TestDefault(t *testing.T) {
 	if cfg.Match.MaxTicks != 1000 {
 		t.Errorf("expected MaxTicks=1000, got %d", cfg.Match.MaxTicks)
 	}
+	if cfg.Match.FriendlyFire {
+		t.Error("expected FriendlyFire=false")
+	}
+	if cfg.Piles.Enabled || cfg.Piles.Interval != 50 || cfg.Piles.Amount != 5 {
+		t.Errorf("expected piles disabled with Interval=50 and Amount=5, got %+v", cfg.Piles)
+	}
+	if cfg.Zone.Enabled || cfg.Zone.DamageInterval != 10 {
+		t.Errorf("expected the zone disabled with DamageInterval=10, got %+v", cfg.Zone)
+	}
+	if cfg.Match.Spawn != SpawnRandom || cfg.Match.MinSpawnDistance != 200 {
+		t.Errorf("expected random spawn with MinSpawnDistance=200, got %q %v", cfg.Match.Spawn, cfg.Match.MinSpawnDistance)
+	}
 
 	// Field
 	if cfg.Field.Width != 1000 {
@@ -34,6 +47,12 @@ func TestDefault(t *testing.T) {
 	if cfg.Snowbot.MaxFlyingSnowball != 3 {
 		t.Errorf("expected MaxFlyingSnowball=3, got %d", cfg.Snowbot.MaxFlyingSnowball)
 	}
+	if cfg.Snowbot.GatherTicks != 5 {
+		t.Errorf("expected GatherTicks=5, got %d", cfg.Snowbot.GatherTicks)
+	}
+	if cfg.Snowbot.GatherAmount != 5 {
+		t.Errorf("expected GatherAmount=5, got %d", cfg.Snowbot.GatherAmount)
+	}
 
 	// Snowball
 	if cfg.Snowball.MaxFlyingDistance != 100 {
@@ -48,17 +67,52 @@ func TestDefault(t *testing.T) {
 	if cfg.Snowball.Damage != 10 {
 		t.Errorf("expected Damage=10, got %d", cfg.Snowball.Damage)
 	}
+	if cfg.Snowball.HitMode != HitModeLanding {
+		t.Errorf("expected HitMode=%q, got %q", HitModeLanding, cfg.Snowball.HitMode)
+	}
+	if cfg.Snowball.TossOrigin != TossOriginPostMove {
+		t.Errorf("expected TossOrigin=%q, got %q", TossOriginPostMove, cfg.Snowball.TossOrigin)
+	}
 
 	// Runtime
 	if cfg.Runtime.MaxMemoryBytes != 10485760 {
 		t.Errorf("expected MaxMemoryBytes=10485760, got %d", cfg.Runtime.MaxMemoryBytes)
 	}
+	if cfg.Runtime.WasmMaxMemoryBytes != 16777216 {
+		t.Errorf("expected WasmMaxMemoryBytes=16777216, got %d", cfg.Runtime.WasmMaxMemoryBytes)
+	}
 	if cfg.Runtime.MaxStackBytes != 1048576 {
 		t.Errorf("expected MaxStackBytes=1048576, got %d", cfg.Runtime.MaxStackBytes)
 	}
+	if cfg.Runtime.MaxInstructionsPerTick != 1000000 {
+		t.Errorf("expected MaxInstructionsPerTick=1000000, got %d", cfg.Runtime.MaxInstructionsPerTick)
+	}
 	if cfg.Runtime.TickTimeoutMs != 100 {
 		t.Errorf("expected TickTimeoutMs=100, got %d", cfg.Runtime.TickTimeoutMs)
 	}
+
+	// Sensor
+	if cfg.Sensor.Detail != SensorBasic {
+		t.Errorf("expected Detail=%q, got %q", SensorBasic, cfg.Sensor.Detail)
+	}
+
+	// Radio
+	if cfg.Radio.MaxMessageBytes != 256 {
+		t.Errorf("expected MaxMessageBytes=256, got %d", cfg.Radio.MaxMessageBytes)
+	}
+	if cfg.Radio.MaxBytesPerTick != 1024 {
+		t.Errorf("expected MaxBytesPerTick=1024, got %d", cfg.Radio.MaxBytesPerTick)
+	}
+
+	// Storage
+	if cfg.Storage.MaxBytes != 4096 {
+		t.Errorf("expected Storage.MaxBytes=4096, got %d", cfg.Storage.MaxBytes)
+	}
+
+	// Energy
+	if cfg.Energy.Enabled {
+		t.Error("expected Energy.Enabled=false")
+	}
 }
 
 func TestLoad_Success(t *testing.T) {
@@ -85,6 +139,7 @@ damage = 20
 [runtime]
 max_memory_bytes = 5242880
 max_stack_bytes = 524288
+max_instructions_per_tick = 200000
 tick_timeout_ms = 50
 `
 	tmpfile, err := os.CreateTemp("", "config_test_*.toml")
@@ -116,6 +171,9 @@ tick_timeout_ms = 50
 	if cfg.Snowbot.MinMove != 2 {
 		t.Errorf("expected MinMove=2, got %d", cfg.Snowbot.MinMove)
 	}
+	if cfg.Runtime.MaxInstructionsPerTick != 200000 {
+		t.Errorf("expected MaxInstructionsPerTick=200000, got %d", cfg.Runtime.MaxInstructionsPerTick)
+	}
 	if cfg.Runtime.TickTimeoutMs != 50 {
 		t.Errorf("expected TickTimeoutMs=50, got %d", cfg.Runtime.TickTimeoutMs)
 	}
@@ -165,3 +223,126 @@ invalid toml syntax
 		t.Errorf("expected default MaxTicks=1000, got %d", cfg.Match.MaxTicks)
 	}
 }
+
+func TestHash(t *testing.T) {
+	a := Default()
+	b := Default()
+	if a.Hash() != b.Hash() {
+		t.Errorf("expected identical configs to hash equally")
+	}
+	b.Snowball.Damage++
+	if a.Hash() == b.Hash() {
+		t.Errorf("expected different configs to hash differently")
+	}
+}
+
+func TestLoad_Obstacles(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.toml")
+	content := `[[field.obstacles]]
+shape = "rect"
+x = 0
+y = 100
+width = 50
+height = 20
+
+[[field.obstacles]]
+shape = "circle"
+x = -200
+y = 0
+radius = 30
+`
+	if err := os.WriteFile(valid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Field.Obstacles) != 2 {
+		t.Fatalf("expected 2 obstacles, got %d", len(cfg.Field.Obstacles))
+	}
+	if o := cfg.Field.Obstacles[1]; o.Shape != ShapeCircle || o.X != -200 || o.Radius != 30 {
+		t.Errorf("unexpected circle obstacle: %+v", o)
+	}
+
+	invalid := filepath.Join(dir, "invalid.toml")
+	content = `[[field.obstacles]]
+shape = "triangle"
+`
+	if err := os.WriteFile(invalid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(invalid)
+	if err == nil {
+		t.Error("expected error for unknown obstacle shape")
+	}
+	if len(cfg.Field.Obstacles) != 0 {
+		t.Errorf("expected defaults without obstacles, got %+v", cfg.Field.Obstacles)
+	}
+}
+
+func TestLoad_SpawnPoints(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.toml")
+	content := `[match]
+spawn = "fixed"
+
+[[match.spawn_points]]
+x = -100
+y = 0
+angle = 90
+
+[[match.spawn_points]]
+x = 100
+y = 0
+angle = 270
+`
+	if err := os.WriteFile(valid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Match.SpawnPoints) != 2 {
+		t.Fatalf("expected 2 spawn points, got %d", len(cfg.Match.SpawnPoints))
+	}
+	if sp := cfg.Match.SpawnPoints[1]; sp.X != 100 || sp.Angle != 270 {
+		t.Errorf("unexpected spawn point: %+v", sp)
+	}
+
+	for name, content := range map[string]string{
+		"unknown layout":   "[match]\nspawn = \"grid\"\n",
+		"no spawn points":  "[match]\nspawn = \"fixed\"\n",
+		"outside field":    "[match]\nspawn = \"fixed\"\n\n[[match.spawn_points]]\nx = 900\ny = 0\n",
+		"negative minimum": "[match]\nmin_spawn_distance = -1\n",
+		"inside obstacle":  "[match]\nspawn = \"fixed\"\n\n[[match.spawn_points]]\nx = 10\ny = 0\n\n[[field.obstacles]]\nshape = \"circle\"\nx = 0\ny = 0\nradius = 20\n",
+	} {
+		path := filepath.Join(dir, "invalid.toml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load(path)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if cfg.Match.Spawn != SpawnRandom || len(cfg.Match.SpawnPoints) != 0 {
+			t.Errorf("%s: expected defaults, got %q %+v", name, cfg.Match.Spawn, cfg.Match.SpawnPoints)
+		}
+	}
+}
+
+func TestLoad_InvalidZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zone.toml")
+	if err := os.WriteFile(path, []byte("[zone]\nenabled = true\ndamage_interval = 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err == nil {
+		t.Error("expected error for zone.damage_interval = 0")
+	}
+	if cfg.Zone.Enabled {
+		t.Errorf("expected defaults with the zone disabled, got %+v", cfg.Zone)
+	}
+}
@@ -0,0 +1,51 @@
+package game
+
+import (
+	"math"
+	"snowfight/internal/config"
+)
+
+// ActionCost returns the energy an action costs, or 0 when the energy economy is disabled.
+func ActionCost(cfg *config.Config, action Action) float64 {
+	if !cfg.Energy.Enabled {
+		return 0
+	}
+	switch action.Type {
+	case ActionMove:
+		return math.Abs(action.Value) * cfg.Energy.MoveCost
+	case ActionTurn:
+		return math.Abs(action.Value) * cfg.Energy.TurnCost
+	case ActionToss:
+		return cfg.Energy.TossCost
+	case ActionScan:
+		return cfg.Energy.ScanCost
+	}
+	return 0
+}
+
+// spendEnergy deducts the cost of an action from the player and reports whether it could be paid.
+func (e *Engine) spendEnergy(p *Player, action Action) bool {
+	cost := ActionCost(e.Config, action)
+	if cost <= 0 {
+		return true
+	}
+	if cost > p.Energy {
+		return false
+	}
+	p.Energy -= cost
+	return true
+}
+
+// regenerateEnergy refills the energy of the players still in the match, up to the pool size.
+func (e *Engine) regenerateEnergy() {
+	if !e.Config.Energy.Enabled {
+		return
+	}
+	for i := range e.State.Players {
+		p := &e.State.Players[i]
+		if !p.Alive() {
+			continue
+		}
+		p.Energy = math.Min(e.Config.Energy.MaxEnergy, p.Energy+e.Config.Energy.RegenPerTick)
+	}
+}
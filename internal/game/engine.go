@@ -11,11 +11,27 @@ import (
 type Engine struct {
 	State          GameState
 	Config         *config.Config
+	Seed           int64 // RNG seed actually used (config seed or time-based)
+	Obstacles      []Obstacle
 	nextSnowballID int
+	nextPileID     int
+	rng            *rand.Rand // seeded by Seed; spawns, then piles
+	stats          []playerStats
+	eliminations   []Elimination // eliminations during the last Update
+	desiredSpeed   []float64     // speed requested by move() during the current Update (inertia only)
 }
 
+// playerStats accumulates per-player match statistics for Result.
+type playerStats struct {
+	damageDealt    int
+	damageReceived int
+}
+
+// maxSpawnAttempts bounds how often a random spawn position is re-rolled.
+const maxSpawnAttempts = 100
+
 // NewGame creates a new game engine with initial state for n players (1-based IDs).
-// Players are spawned at random positions/angles within the field.
+// Players are placed according to match.spawn (see spawnPoses).
 func NewGame(cfg *config.Config, numPlayers int) *Engine {
 	if numPlayers < 1 {
 		numPlayers = 1
@@ -30,105 +46,328 @@ func NewGame(cfg *config.Config, numPlayers int) *Engine {
 	}
 	rng := rand.New(rand.NewSource(seed))
 
-	halfWidth := float64(cfg.Field.Width) / 2
-	halfHeight := float64(cfg.Field.Height) / 2
+	obstacles := ObstaclesFromConfig(cfg)
 
 	players := make([]Player, numPlayers)
-	for i := 0; i < numPlayers; i++ {
+	for i, start := range spawnPoses(cfg, obstacles, rng, numPlayers) {
 		players[i] = Player{
-			X:             rng.Float64()*2*halfWidth - halfWidth,
-			Y:             rng.Float64()*2*halfHeight - halfHeight,
+			X:             start.X,
+			Y:             start.Y,
 			HP:            cfg.Snowbot.MaxHP,
-			Angle:         rng.Float64() * 360,
+			Angle:         start.Angle,
 			SnowballCount: cfg.Snowbot.MaxSnowball,
 		}
+		if cfg.Energy.Enabled {
+			players[i].Energy = cfg.Energy.MaxEnergy
+		}
 	}
 
 	engine := &Engine{
 		Config:         cfg,
+		Seed:           seed,
+		Obstacles:      obstacles,
 		nextSnowballID: 1,
+		nextPileID:     1,
+		rng:            rng,
+		stats:          make([]playerStats, numPlayers),
+		desiredSpeed:   make([]float64, numPlayers),
 		State: GameState{
 			Tick:      0,
 			Snowballs: []Snowball{},
 			Players:   players,
+			Zone:      ZoneAt(cfg, 0),
+			Piles:     []Pile{},
 		},
 	}
 	engine.syncLegacyPlayers()
 	return engine
 }
 
+// pose is where a bot stands and faces.
+type pose struct {
+	X, Y, Angle float64
+}
+
 // Update advances the game state by one tick.
 // actions is a slice per player (1-based indexing).
+//
+// Actions are resolved in phases, each applied to every player before the next one, so the
+// outcome does not depend on player order or on the order a bot issued its calls: gathering
+// (which cancels a move in the same tick), turns, moves and pile pickups, then tosses from the
+// pose selected by snowball.toss_origin.
 func (e *Engine) Update(actions [][]Action) {
 	e.State.Tick++
+	e.State.Zone = ZoneAt(e.Config, e.State.Tick)
+	e.eliminations = nil
+	for i := range e.desiredSpeed {
+		e.desiredSpeed[i] = 0
+	}
 
+	// Only players alive at the start of the tick act
+	acting := make([][]Action, len(actions))
 	for idx, acts := range actions {
-		playerID := idx + 1
-		p := e.State.PlayerRef(playerID)
-		if p == nil {
-			continue
+		if p := e.State.PlayerRef(idx + 1); p != nil && p.Alive() {
+			acting[idx] = acts
 		}
+	}
+
+	origins := make([]pose, len(acting))
+	if e.Config.Snowball.TossOrigin == config.TossOriginPreMove {
+		e.recordPoses(origins)
+	}
+
+	e.applyPhase(acting, ActionGather)
+	e.applyPhase(acting, ActionScan)
+	e.applyPhase(acting, ActionTurn)
+	e.applyPhase(acting, ActionMove)
+	e.updateInertia(acting)
+	e.collectPiles()
+
+	if e.Config.Snowball.TossOrigin != config.TossOriginPreMove {
+		e.recordPoses(origins)
+	}
+	for idx, acts := range acting {
+		p := e.State.PlayerRef(idx + 1)
 		for _, action := range acts {
-			e.applyAction(p, playerID, action)
+			if action.Type == ActionToss {
+				e.toss(p, idx+1, action, origins[idx])
+			}
 		}
 	}
 
+	e.updateGathering()
 	e.updateSnowballs()
+	e.applyZoneDamage()
+	e.spawnPile()
+	e.regenerateEnergy()
 	e.syncLegacyPlayers()
 }
 
+// applyPhase applies the actions of one type for every player, in the order each bot issued them.
+func (e *Engine) applyPhase(actions [][]Action, actionType ActionType) {
+	for idx, acts := range actions {
+		p := e.State.PlayerRef(idx + 1)
+		for _, action := range acts {
+			if action.Type == actionType {
+				e.applyAction(p, idx+1, action)
+			}
+		}
+	}
+}
+
+// recordPoses stores the current pose of each player in poses (indexed by player ID - 1).
+func (e *Engine) recordPoses(poses []pose) {
+	for i := range poses {
+		if p := e.State.PlayerRef(i + 1); p != nil {
+			poses[i] = pose{X: p.X, Y: p.Y, Angle: p.Angle}
+		}
+	}
+}
+
 func (e *Engine) applyAction(p *Player, playerID int, action Action) {
 	switch action.Type {
 	case ActionMove:
-		// 0° = north (Y+), 90° = east (X+), 180° = south (Y-), 270° = west (X-)
-		rad := p.Angle * math.Pi / 180.0
-		newX := p.X + math.Sin(rad)*action.Value
-		newY := p.Y + math.Cos(rad)*action.Value
-
-		halfWidth := float64(e.Config.Field.Width) / 2
-		halfHeight := float64(e.Config.Field.Height) / 2
-		p.X = math.Max(-halfWidth, math.Min(halfWidth, newX))
-		p.Y = math.Max(-halfHeight, math.Min(halfHeight, newY))
-		p.X = math.Round(p.X)
-		p.Y = math.Round(p.Y)
+		if p.GatherTicks > 0 {
+			return
+		}
+		if e.Config.Snowbot.Acceleration > 0 {
+			// With inertia the move sets the speed to accelerate toward; the distance actually
+			// travelled is paid for in updateInertia
+			if playerID-1 < len(e.desiredSpeed) {
+				e.desiredSpeed[playerID-1] = action.Value
+			}
+			return
+		}
+		if !e.spendEnergy(p, action) {
+			return
+		}
+		e.moveForward(p, action.Value)
 	case ActionTurn:
-		p.Angle += action.Value
+		degrees := action.Value
+		if limit := float64(e.Config.Snowbot.MaxTurnPerTick); limit > 0 {
+			degrees = math.Max(-limit, math.Min(limit, degrees))
+		}
+		if !e.spendEnergy(p, Action{Type: ActionTurn, Value: degrees}) {
+			return
+		}
+		p.Angle += degrees
 		p.Angle = math.Mod(p.Angle, 360)
 		if p.Angle < 0 {
 			p.Angle += 360
 		}
-	case ActionToss:
-		if p.SnowballCount <= 0 {
+	case ActionGather:
+		if p.GatherTicks > 0 || e.Config.Snowbot.GatherAmount <= 0 || p.SnowballCount >= e.Config.Snowbot.MaxSnowball {
 			return
 		}
+		p.GatherTicks = e.Config.Snowbot.GatherTicks
+		if p.GatherTicks < 1 {
+			p.GatherTicks = 1
+		}
+	case ActionScan:
+		e.spendEnergy(p, action)
+	}
+}
+
+// toss throws a snowball from origin if the player has one and is under the flying limit.
+func (e *Engine) toss(p *Player, playerID int, action Action, origin pose) {
+	if p.SnowballCount <= 0 {
+		return
+	}
+
+	flyingCount := 0
+	for _, sb := range e.State.Snowballs {
+		if sb.OwnerID == playerID {
+			flyingCount++
+		}
+	}
+	if flyingCount >= e.Config.Snowbot.MaxFlyingSnowball || !e.spendEnergy(p, action) {
+		return
+	}
+
+	rad := origin.Angle * math.Pi / 180.0
+	speed := float64(e.Config.Snowball.Speed)
+
+	snowball := Snowball{
+		ID:       e.nextSnowballID,
+		OwnerID:  playerID,
+		X:        origin.X,
+		Y:        origin.Y,
+		VX:       math.Sin(rad) * speed,
+		VY:       math.Cos(rad) * speed,
+		Target:   float64(action.ThrowDistance),
+		Traveled: 0,
+	}
+	e.State.Snowballs = append(e.State.Snowballs, snowball)
+	e.nextSnowballID++
+	p.SnowballCount--
+}
+
+// moveForward moves the player distance units along its heading, clamped to the field and
+// rounded to whole units, and reports whether the field edge or an obstacle cut the move short.
+func (e *Engine) moveForward(p *Player, distance float64) bool {
+	// 0° = north (Y+), 90° = east (X+), 180° = south (Y-), 270° = west (X-)
+	rad := p.Angle * math.Pi / 180.0
+	targetX := p.X + math.Sin(rad)*distance
+	targetY := p.Y + math.Cos(rad)*distance
+
+	halfWidth := float64(e.Config.Field.Width) / 2
+	halfHeight := float64(e.Config.Field.Height) / 2
+	precision := e.Config.Field.PositionPrecision
+	newX := quantize(math.Max(-halfWidth, math.Min(halfWidth, targetX)), precision)
+	newY := quantize(math.Max(-halfHeight, math.Min(halfHeight, targetY)), precision)
+	x, y := e.blockedMove(p.X, p.Y, newX, newY)
+	p.X, p.Y = x, y
+	return x != quantize(targetX, precision) || y != quantize(targetY, precision)
+}
 
-		flyingCount := 0
-		for _, sb := range e.State.Snowballs {
-			if sb.OwnerID == playerID {
-				flyingCount++
+// updateInertia accelerates every player toward the speed its move() requested this tick
+// (0 without a move) and moves it along its heading. Gathering or hitting an obstacle stops a bot.
+// With the energy economy the bot pays for the distance it actually travels, including while it
+// slows down, and its speed is limited to what it can pay after this tick's toss.
+func (e *Engine) updateInertia(actions [][]Action) {
+	accel := float64(e.Config.Snowbot.Acceleration)
+	if accel <= 0 {
+		return
+	}
+	for i := range e.State.Players {
+		p := &e.State.Players[i]
+		if !p.Alive() || p.GatherTicks > 0 {
+			p.Speed = 0
+			continue
+		}
+		desired := 0.0
+		if i < len(e.desiredSpeed) {
+			desired = e.desiredSpeed[i]
+		}
+		p.Speed += math.Max(-accel, math.Min(accel, desired-p.Speed))
+		if p.Speed == 0 {
+			continue
+		}
+
+		if e.Config.Energy.Enabled && e.Config.Energy.MoveCost > 0 {
+			available := p.Energy
+			if i < len(actions) {
+				for _, action := range actions[i] {
+					if action.Type == ActionToss {
+						available -= ActionCost(e.Config, action)
+					}
+				}
+			}
+			maxSpeed := math.Max(0, available) / e.Config.Energy.MoveCost
+			p.Speed = math.Max(-maxSpeed, math.Min(maxSpeed, p.Speed))
+			if p.Speed == 0 {
+				continue
 			}
 		}
-		if flyingCount >= e.Config.Snowbot.MaxFlyingSnowball {
-			return
+
+		x, y := p.X, p.Y
+		stopped := e.moveForward(p, p.Speed)
+		travelled := math.Hypot(p.X-x, p.Y-y)
+		p.Energy = math.Max(0, p.Energy-ActionCost(e.Config, Action{Type: ActionMove, Value: travelled}))
+		if stopped {
+			p.Speed = 0
 		}
+	}
+}
+
+// quantize rounds a coordinate to the given number of decimal places (field.position_precision).
+func quantize(v float64, precision int) float64 {
+	if precision <= 0 {
+		return math.Round(v)
+	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(v*scale) / scale
+}
+
+// moveBy advances the snowball by (dx, dy) from its exact position and rounds X/Y to precision.
+// At precision 0 snowball positions are not rounded at all, as before field.position_precision existed.
+func (sb *Snowball) moveBy(dx, dy float64, precision int) {
+	if precision <= 0 {
+		sb.X += dx
+		sb.Y += dy
+		return
+	}
+	exactX := sb.X + sb.roundingX + dx
+	exactY := sb.Y + sb.roundingY + dy
+	sb.X = quantize(exactX, precision)
+	sb.Y = quantize(exactY, precision)
+	sb.roundingX = exactX - sb.X
+	sb.roundingY = exactY - sb.Y
+}
 
-		angle := p.Angle
-		rad := angle * math.Pi / 180.0
-		speed := float64(e.Config.Snowball.Speed)
+// blockedMove returns where a move from (x, y) to (newX, newY) ends when obstacles are in the way:
+// the bot stops at the last whole step before the first obstacle it would enter.
+func (e *Engine) blockedMove(x, y, newX, newY float64) (float64, float64) {
+	if _, _, hit := firstObstacleHit(e.Obstacles, x, y, newX, newY); !hit {
+		return newX, newY
+	}
+	dx := newX - x
+	dy := newY - y
+	dist := math.Sqrt(dx*dx + dy*dy)
+	for step := math.Floor(dist); step >= 1; step-- {
+		cx := quantize(x+dx*step/dist, e.Config.Field.PositionPrecision)
+		cy := quantize(y+dy*step/dist, e.Config.Field.PositionPrecision)
+		if _, _, hit := firstObstacleHit(e.Obstacles, x, y, cx, cy); !hit && !insideObstacle(e.Obstacles, cx, cy) {
+			return cx, cy
+		}
+	}
+	return x, y
+}
 
-		snowball := Snowball{
-			ID:       e.nextSnowballID,
-			OwnerID:  playerID,
-			X:        p.X,
-			Y:        p.Y,
-			VX:       math.Sin(rad) * speed,
-			VY:       math.Cos(rad) * speed,
-			Target:   float64(action.ThrowDistance),
-			Traveled: 0,
+// updateGathering counts down gathering players and adds snowballs (up to the cap) when done.
+func (e *Engine) updateGathering() {
+	for i := range e.State.Players {
+		p := &e.State.Players[i]
+		if p.GatherTicks <= 0 || !p.Alive() {
+			continue
+		}
+		p.GatherTicks--
+		if p.GatherTicks == 0 {
+			p.SnowballCount += e.Config.Snowbot.GatherAmount
+			if p.SnowballCount > e.Config.Snowbot.MaxSnowball {
+				p.SnowballCount = e.Config.Snowbot.MaxSnowball
+			}
 		}
-		e.State.Snowballs = append(e.State.Snowballs, snowball)
-		e.nextSnowballID++
-		p.SnowballCount--
 	}
 }
 
@@ -138,11 +377,32 @@ func (e *Engine) updateSnowballs() {
 	damageRadius := float64(e.Config.Snowball.DamageRadius)
 	speed := float64(e.Config.Snowball.Speed)
 
+	pathMode := e.Config.Snowball.HitMode == config.HitModePath
+
 	remaining := []Snowball{}
 
 	for _, sb := range e.State.Snowballs {
-		sb.X += sb.VX
-		sb.Y += sb.VY
+		_, obstacleT, blocked := firstObstacleHit(e.Obstacles, sb.X, sb.Y, sb.X+sb.VX, sb.Y+sb.VY)
+
+		if pathMode {
+			// Sweep this tick's segment, up to an obstacle or the target distance, against all bots
+			end := 1.0
+			if blocked {
+				end = obstacleT
+			}
+			if left := sb.Target - sb.Traveled; speed > 0 && left < speed*end {
+				end = math.Max(0, left/speed)
+			}
+			if e.sweepSnowball(&sb, end, speed, damageRadius) {
+				continue
+			}
+		}
+
+		// Snowballs that hit an obstacle are stopped without dealing damage
+		if blocked {
+			continue
+		}
+		sb.moveBy(sb.VX, sb.VY, e.Config.Field.PositionPrecision)
 		sb.Traveled += speed
 
 		if sb.X < -halfWidth || sb.X > halfWidth || sb.Y < -halfHeight || sb.Y > halfHeight {
@@ -150,7 +410,10 @@ func (e *Engine) updateSnowballs() {
 		}
 
 		if sb.Traveled >= sb.Target {
-			e.checkSnowballDamage(&sb, damageRadius)
+			// In path mode the landing point was already covered by the sweep
+			if !pathMode {
+				e.checkSnowballDamage(&sb, damageRadius)
+			}
 			continue
 		}
 
@@ -160,31 +423,190 @@ func (e *Engine) updateSnowballs() {
 	e.State.Snowballs = remaining
 }
 
+// sweepSnowball checks the first end fraction of this tick's movement of sb against every living bot.
+// The first bot within damageRadius of the path takes damage and true is returned (the snowball is consumed).
+// The thrower cannot be hit until the snowball has travelled snowball.owner_immunity.
+func (e *Engine) sweepSnowball(sb *Snowball, end, speed, damageRadius float64) bool {
+	hit := -1
+	best := 0.0
+	for i, p := range e.State.Players {
+		if !p.Alive() || e.friendlyFireBlocked(sb.OwnerID, i+1) {
+			continue
+		}
+		start := 0.0
+		if i+1 == sb.OwnerID && speed > 0 {
+			start = (float64(e.Config.Snowball.OwnerImmunity) - sb.Traveled) / speed
+			if start < 0 {
+				start = 0
+			}
+		}
+		if start > end {
+			continue
+		}
+		t, ok := segmentCircleEntry(
+			sb.X+sb.VX*start, sb.Y+sb.VY*start,
+			sb.X+sb.VX*end, sb.Y+sb.VY*end,
+			p.X, p.Y, damageRadius,
+		)
+		if !ok {
+			continue
+		}
+		t = start + t*(end-start)
+		if hit < 0 || t < best {
+			hit = i
+			best = t
+		}
+	}
+	if hit < 0 {
+		return false
+	}
+	e.damagePlayer(hit, sb.OwnerID, e.Config.Snowball.Damage)
+	return true
+}
+
 func (e *Engine) checkSnowballDamage(sb *Snowball, damageRadius float64) {
 	for i := range e.State.Players {
 		// Skip owner damage? original allowed hitting self? leave as is (can self-hit)
 		p := &e.State.Players[i]
+		if !p.Alive() || e.friendlyFireBlocked(sb.OwnerID, i+1) {
+			continue
+		}
 		dx := p.X - sb.X
 		dy := p.Y - sb.Y
 		dist := math.Sqrt(dx*dx + dy*dy)
 		if dist <= damageRadius {
-			p.HP -= e.Config.Snowball.Damage
-			if p.HP < 0 {
-				p.HP = 0
-			}
+			e.damagePlayer(i, sb.OwnerID, e.Config.Snowball.Damage)
 		}
 	}
 }
 
-// IsGameOver returns true if only one or zero players have HP > 0.
+// friendlyFireBlocked reports whether ownerID's snowballs pass through player targetID because
+// they are teammates and match.friendly_fire is off. Self-hits are unaffected.
+func (e *Engine) friendlyFireBlocked(ownerID, targetID int) bool {
+	return !e.Config.Match.FriendlyFire && e.State.Allies(ownerID, targetID)
+}
+
+// damagePlayer applies damage from ownerID's snowball (0 for the zone) to the player at index i.
+func (e *Engine) damagePlayer(i, ownerID, damage int) {
+	p := &e.State.Players[i]
+	before := p.HP
+	p.HP -= damage
+	if p.HP < 0 {
+		p.HP = 0
+	}
+	e.recordDamage(ownerID, i+1, before-p.HP)
+	if !p.Alive() {
+		p.EliminatedAt = e.State.Tick
+		e.eliminations = append(e.eliminations, Elimination{Tick: e.State.Tick, PlayerID: i + 1, By: ownerID})
+	}
+}
+
+// Eliminations returns the players eliminated during the last Update, in order.
+func (e *Engine) Eliminations() []Elimination {
+	return e.eliminations
+}
+
+// recordDamage updates statistics after player targetID lost damage HP to ownerID's snowball.
+func (e *Engine) recordDamage(ownerID, targetID, damage int) {
+	target := e.playerStats(targetID)
+	target.damageReceived += damage
+	if ownerID != targetID {
+		if owner := e.playerStats(ownerID); owner != nil {
+			owner.damageDealt += damage
+		}
+	}
+}
+
+// playerStats returns the statistics for a 1-based player ID, growing the slice as needed.
+func (e *Engine) playerStats(id int) *playerStats {
+	if id <= 0 {
+		return nil
+	}
+	for len(e.stats) < id {
+		e.stats = append(e.stats, playerStats{})
+	}
+	return &e.stats[id-1]
+}
+
+// Result summarizes the current state as a finished match.
+// Players fight as sides: their team, or themselves without one. The winning side is the one whose
+// survivors have the most HP in total; all its members are winners. Ties yield several winners (a draw).
+func (e *Engine) Result() Result {
+	result := Result{
+		Tick:    e.State.Tick,
+		Seed:    e.Seed,
+		Winners: []int{},
+		Players: make([]PlayerResult, len(e.State.Players)),
+	}
+
+	sides := map[int]bool{}
+	score := map[int]int{} // total HP of each side with survivors
+	for i, p := range e.State.Players {
+		id := i + 1
+		stats := e.playerStats(id)
+		survived := e.State.Tick
+		if !p.Alive() && p.EliminatedAt > 0 {
+			survived = p.EliminatedAt
+		}
+		result.Players[i] = PlayerResult{
+			ID:             id,
+			Team:           p.Team,
+			HP:             p.HP,
+			TicksSurvived:  survived,
+			DamageDealt:    stats.damageDealt,
+			DamageReceived: stats.damageReceived,
+		}
+		side := e.State.side(id)
+		sides[side] = true
+		if p.Alive() {
+			score[side] += p.HP
+		}
+	}
+	best := 0
+	for _, s := range score {
+		if s > best {
+			best = s
+		}
+	}
+	for i := range e.State.Players {
+		if s, ok := score[e.State.side(i+1)]; ok && s == best {
+			result.Winners = append(result.Winners, i+1)
+		}
+	}
+
+	switch {
+	case len(score) == 0:
+		result.Reason = EndAllDead
+	case len(score) == 1 && len(sides) > 1:
+		result.Reason = EndLastBotStanding
+		if e.State.PlayerRef(result.Winners[0]).Team > 0 {
+			result.Reason = EndLastTeamStanding
+		}
+	default:
+		result.Reason = EndMaxTicks
+	}
+	return result
+}
+
+// IsGameOver returns true if only one or zero sides (teams, or players without a team) have survivors.
 func (e *Engine) IsGameOver() bool {
-	alive := 0
-	for _, p := range e.State.Players {
-		if p.HP > 0 {
-			alive++
+	alive := map[int]bool{}
+	for i, p := range e.State.Players {
+		if p.Alive() {
+			alive[e.State.side(i+1)] = true
 		}
 	}
-	return alive <= 1
+	return len(alive) <= 1
+}
+
+// SetTeams assigns team numbers to players in order (0 = no team).
+func (e *Engine) SetTeams(teams []int) {
+	for i, team := range teams {
+		if i < len(e.State.Players) {
+			e.State.Players[i].Team = team
+		}
+	}
+	e.syncLegacyPlayers()
 }
 
 // syncLegacyPlayers copies first two players (if present) into P1/P2 fields for backward compatibility.
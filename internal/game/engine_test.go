@@ -1,6 +1,8 @@
 package game
 
 import (
+	"math"
+	"math/rand"
 	"snowfight/internal/config"
 	"testing"
 )
@@ -91,3 +93,842 @@ func TestSnowball_TargetReach(t *testing.T) {
 		t.Errorf("expected snowball removed on target reach, got %d", len(engine.State.Snowballs))
 	}
 }
+
+func TestGather_RefillsAfterTicks(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.MaxSnowball = 10
+	cfg.Snowbot.GatherTicks = 3
+	cfg.Snowbot.GatherAmount = 4
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Players[0].SnowballCount = 8
+
+	// Move issued before make_snowball in the same tick is still cancelled.
+	engine.Update([][]Action{{{Type: ActionMove, Value: 10}, {Type: ActionGather}}, {}})
+	if engine.State.P1.Y != 0 {
+		t.Errorf("expected move to be ignored while gathering, got Y=%f", engine.State.P1.Y)
+	}
+	if engine.State.P1.GatherTicks != 2 {
+		t.Errorf("expected 2 gather ticks remaining, got %d", engine.State.P1.GatherTicks)
+	}
+
+	engine.Update([][]Action{{{Type: ActionMove, Value: 10}}, {}})
+	if engine.State.P1.Y != 0 || engine.State.P1.SnowballCount != 8 {
+		t.Errorf("expected no move and no refill yet, got Y=%f count=%d", engine.State.P1.Y, engine.State.P1.SnowballCount)
+	}
+
+	engine.Update([][]Action{{}, {}})
+	if engine.State.P1.GatherTicks != 0 {
+		t.Errorf("expected gathering finished, got %d ticks remaining", engine.State.P1.GatherTicks)
+	}
+	if engine.State.P1.SnowballCount != 10 {
+		t.Errorf("expected refill capped at 10, got %d", engine.State.P1.SnowballCount)
+	}
+
+	engine.Update([][]Action{{{Type: ActionMove, Value: 10}}, {}})
+	if engine.State.P1.Y != 10 {
+		t.Errorf("expected move after gathering, got Y=%f", engine.State.P1.Y)
+	}
+}
+
+func TestGather_IgnoredWhenFull(t *testing.T) {
+	cfg := config.Default()
+	engine := newEngineWithTwoPlayers(cfg)
+
+	engine.Update([][]Action{{{Type: ActionGather}, {Type: ActionMove, Value: 10}}, {}})
+	if engine.State.P1.GatherTicks != 0 {
+		t.Errorf("expected no gathering at full inventory, got %d", engine.State.P1.GatherTicks)
+	}
+	if engine.State.P1.Y != 10 {
+		t.Errorf("expected move to apply, got Y=%f", engine.State.P1.Y)
+	}
+}
+
+func TestResult_DamageAndWinner(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.MaxHP = 10
+	cfg.Snowball.Damage = 10
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Snowballs = []Snowball{
+		{ID: 1, OwnerID: 1, X: 40, Y: 0, VX: 10, VY: 0, Target: 50, Traveled: 40},
+	}
+
+	engine.Update([][]Action{{}, {}})
+	if !engine.IsGameOver() {
+		t.Fatalf("expected game over after P2 is hit")
+	}
+
+	result := engine.Result()
+	if result.Reason != EndLastBotStanding {
+		t.Errorf("expected reason %s, got %s", EndLastBotStanding, result.Reason)
+	}
+	if len(result.Winners) != 1 || result.Winners[0] != 1 {
+		t.Errorf("expected winner [1], got %v", result.Winners)
+	}
+	if result.Seed != 1 {
+		t.Errorf("expected seed 1, got %d", result.Seed)
+	}
+	if result.Players[0].DamageDealt != 10 || result.Players[1].DamageReceived != 10 {
+		t.Errorf("expected 10 damage dealt/received, got %+v", result.Players)
+	}
+	if result.Players[1].TicksSurvived != 1 {
+		t.Errorf("expected P2 to survive 1 tick, got %d", result.Players[1].TicksSurvived)
+	}
+}
+
+func TestResult_MaxTicksDraw(t *testing.T) {
+	cfg := config.Default()
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.Update([][]Action{{}, {}})
+
+	result := engine.Result()
+	if result.Reason != EndMaxTicks {
+		t.Errorf("expected reason %s, got %s", EndMaxTicks, result.Reason)
+	}
+	if len(result.Winners) != 2 {
+		t.Errorf("expected draw between both players, got %v", result.Winners)
+	}
+}
+
+func TestObstacle_BlocksMove(t *testing.T) {
+	cfg := config.Default()
+	// Rect spanning y in [5, 15] in front of P1 (facing north from (-50, 0))
+	cfg.Field.Obstacles = []config.ObstacleConfig{{Shape: config.ShapeRect, X: -50, Y: 10, Width: 20, Height: 10}}
+	engine := newEngineWithTwoPlayers(cfg)
+
+	engine.Update([][]Action{{{Type: ActionMove, Value: 10}}, {}})
+
+	if engine.State.P1.Y != 4 {
+		t.Errorf("expected P1 to stop at Y=4 in front of the obstacle, got %f", engine.State.P1.Y)
+	}
+}
+
+func TestObstacle_StopsSnowball(t *testing.T) {
+	cfg := config.Default()
+	cfg.Field.Obstacles = []config.ObstacleConfig{{Shape: config.ShapeCircle, X: 0, Y: 0, Radius: 10}}
+	engine := newEngineWithTwoPlayers(cfg)
+	// P1 at (-50, 0) throws east at P2 (50, 0) through the obstacle
+	engine.State.Players[0].Angle = 90
+	engine.Update([][]Action{{{Type: ActionToss, ThrowDistance: 100}}, {}})
+
+	for i := 0; i < 10; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+
+	if len(engine.State.Snowballs) != 0 {
+		t.Errorf("expected snowball stopped by obstacle, got %d", len(engine.State.Snowballs))
+	}
+	if engine.State.P2.HP != cfg.Snowbot.MaxHP {
+		t.Errorf("expected P2 undamaged behind obstacle, got HP=%d", engine.State.P2.HP)
+	}
+}
+
+func TestScan_ObstacleOcclusion(t *testing.T) {
+	cfg := config.Default()
+	cfg.Field.Obstacles = []config.ObstacleConfig{{Shape: config.ShapeRect, X: 0, Y: 0, Width: 10, Height: 40}}
+	engine := newEngineWithTwoPlayers(cfg)
+
+	// P1 at (-50, 0) scanning east: P2 at (50, 0) is hidden, the obstacle face at x=-5 is reported
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20)
+	if len(results) != 1 {
+		t.Fatalf("expected only the obstacle, got %+v", results)
+	}
+	if results[0].Type != "obstacle" || results[0].Distance != 45 {
+		t.Errorf("expected obstacle at distance 45, got %+v", results[0])
+	}
+
+	cfg.Field.Obstacles = nil
+	results = CalculateScan(&engine.State, cfg, nil, 1, 90, 20)
+	if len(results) != 1 || results[0].Type != "snowbot" {
+		t.Errorf("expected P2 visible without obstacles, got %+v", results)
+	}
+}
+
+func TestScan_ObstacleCenterOutsideCone(t *testing.T) {
+	cfg := config.Default()
+	// Wall spanning y in [-2, 58] between the players; its center is at a bearing of about 60 degrees from P1
+	cfg.Field.Obstacles = []config.ObstacleConfig{{Shape: config.ShapeRect, X: 0, Y: 28, Width: 10, Height: 60}}
+	engine := newEngineWithTwoPlayers(cfg)
+
+	// P1 at (-50, 0) scanning east: P2 is hidden and the wall's face at x=-5 is reported
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20)
+	if len(results) != 1 {
+		t.Fatalf("expected only the obstacle, got %+v", results)
+	}
+	if results[0].Type != "obstacle" || math.Abs(results[0].Distance-45) > 1e-6 || math.Abs(results[0].Angle-90) > 1e-6 {
+		t.Errorf("expected obstacle at distance 45 and angle 90, got %+v", results[0])
+	}
+}
+
+func TestPathHit_FirstBotTakesDamage(t *testing.T) {
+	cfg := config.Default()
+	cfg.Match.MaxPlayers = 3
+	cfg.Snowball.HitMode = config.HitModePath
+	cfg.Match.RandomSeed = 1
+	engine := NewGame(cfg, 3)
+	// P1 at (-50, 0) throws east past P2 at (-20, 2) towards P3 at (50, 0)
+	engine.State.Players[0] = Player{X: -50, Y: 0, HP: cfg.Snowbot.MaxHP, Angle: 90, SnowballCount: 1}
+	engine.State.Players[1] = Player{X: -20, Y: 2, HP: cfg.Snowbot.MaxHP}
+	engine.State.Players[2] = Player{X: 50, Y: 0, HP: cfg.Snowbot.MaxHP}
+
+	engine.Update([][]Action{{{Type: ActionToss, ThrowDistance: 100}}, {}, {}})
+	for i := 0; i < 3; i++ {
+		engine.Update([][]Action{{}, {}, {}})
+	}
+
+	if got := engine.State.Players[1].HP; got != cfg.Snowbot.MaxHP-cfg.Snowball.Damage {
+		t.Errorf("expected P2 hit along the path, got HP=%d", got)
+	}
+	if got := engine.State.Players[2].HP; got != cfg.Snowbot.MaxHP {
+		t.Errorf("expected P3 shielded by P2, got HP=%d", got)
+	}
+	if got := engine.State.Players[0].HP; got != cfg.Snowbot.MaxHP {
+		t.Errorf("expected thrower immune, got HP=%d", got)
+	}
+	if len(engine.State.Snowballs) != 0 {
+		t.Errorf("expected snowball consumed, got %d", len(engine.State.Snowballs))
+	}
+}
+
+func TestHitMode_LandingVsPath(t *testing.T) {
+	cfg := config.Default()
+	engine := newEngineWithTwoPlayers(cfg)
+	// P1 throws east over P2 (at distance 100) to land at distance 200
+	engine.State.Players[0].Angle = 90
+	cfg.Snowball.MaxFlyingDistance = 200
+	engine.Update([][]Action{{{Type: ActionToss, ThrowDistance: 200}}, {}})
+	for i := 0; i < 25; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+
+	if got := engine.State.P2.HP; got != cfg.Snowbot.MaxHP {
+		t.Errorf("expected landing-mode snowball to fly over P2, got HP=%d", got)
+	}
+
+	cfg.Snowball.HitMode = config.HitModePath
+	engine = newEngineWithTwoPlayers(cfg)
+	engine.State.Players[0].Angle = 90
+	engine.Update([][]Action{{{Type: ActionToss, ThrowDistance: 200}}, {}})
+	for i := 0; i < 25; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+
+	if got := engine.State.P2.HP; got != cfg.Snowbot.MaxHP-cfg.Snowball.Damage {
+		t.Errorf("expected path-mode snowball to hit P2, got HP=%d", got)
+	}
+}
+
+func TestElimination_StopsActingAndScans(t *testing.T) {
+	cfg := config.Default()
+	cfg.Match.MaxPlayers = 3
+	cfg.Match.RandomSeed = 1
+	engine := NewGame(cfg, 3)
+	engine.State.Players[0] = Player{X: -50, Y: 0, HP: cfg.Snowbot.MaxHP, Angle: 90, SnowballCount: 5}
+	engine.State.Players[1] = Player{X: 0, Y: 0, HP: cfg.Snowball.Damage, SnowballCount: 5}
+	engine.State.Players[2] = Player{X: 50, Y: 0, HP: cfg.Snowbot.MaxHP}
+	engine.State.Snowballs = []Snowball{{ID: 1, OwnerID: 1, X: 0, Y: 0, Target: 10, Traveled: 10}}
+
+	engine.Update([][]Action{{}, {}, {}})
+
+	if engine.State.Players[1].Alive() || engine.State.Players[1].EliminatedAt != 1 {
+		t.Fatalf("expected P2 eliminated at tick 1, got %+v", engine.State.Players[1])
+	}
+	if got := engine.Eliminations(); len(got) != 1 || got[0] != (Elimination{Tick: 1, PlayerID: 2, By: 1}) {
+		t.Errorf("expected one elimination of P2 by P1, got %+v", got)
+	}
+
+	// The eliminated bot's actions are ignored
+	engine.Update([][]Action{{}, {{Type: ActionMove, Value: 10}, {Type: ActionToss, ThrowDistance: 50}}, {}})
+	if p := engine.State.Players[1]; p.Y != 0 || p.SnowballCount != 5 || len(engine.State.Snowballs) != 0 {
+		t.Errorf("expected eliminated P2 not to act, got %+v with %d snowballs", p, len(engine.State.Snowballs))
+	}
+	if len(engine.Eliminations()) != 0 {
+		t.Errorf("expected eliminations to reset each tick, got %+v", engine.Eliminations())
+	}
+
+	// Scanning east from P1 skips the eliminated P2 and finds P3
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20)
+	if len(results) != 1 || results[0].Distance != 100 {
+		t.Errorf("expected only P3 at distance 100, got %+v", results)
+	}
+}
+
+// helper to create a 2v2 engine: team 1 = P1, P2 and team 2 = P3, P4
+func newTeamEngine(cfg *config.Config) *Engine {
+	cfg.Match.MaxPlayers = 4
+	cfg.Match.RandomSeed = 1
+	engine := NewGame(cfg, 4)
+	for i, x := range []float64{-50, 0, 50, 100} {
+		engine.State.Players[i] = Player{X: x, Y: 0, HP: cfg.Snowbot.MaxHP, Angle: 90, SnowballCount: cfg.Snowbot.MaxSnowball}
+	}
+	engine.SetTeams([]int{1, 1, 2, 2})
+	return engine
+}
+
+func TestTeams_FriendlyFire(t *testing.T) {
+	for _, friendlyFire := range []bool{false, true} {
+		cfg := config.Default()
+		cfg.Match.FriendlyFire = friendlyFire
+		engine := newTeamEngine(cfg)
+		// P1 lands a snowball on teammate P2
+		engine.State.Snowballs = []Snowball{{ID: 1, OwnerID: 1, X: 0, Y: 0, Target: 10, Traveled: 10}}
+		engine.Update([][]Action{{}, {}, {}, {}})
+
+		want := cfg.Snowbot.MaxHP
+		if friendlyFire {
+			want -= cfg.Snowball.Damage
+		}
+		if got := engine.State.Players[1].HP; got != want {
+			t.Errorf("friendly_fire=%v: expected teammate HP=%d, got %d", friendlyFire, want, got)
+		}
+	}
+}
+
+func TestTeams_GameOverAndResult(t *testing.T) {
+	cfg := config.Default()
+	engine := newTeamEngine(cfg)
+	engine.State.Players[0].HP = 0
+	engine.State.Players[2].HP = 0
+	if engine.IsGameOver() {
+		t.Fatal("expected match to continue while both teams have survivors")
+	}
+
+	engine.State.Players[3].HP = 0
+	if !engine.IsGameOver() {
+		t.Fatal("expected match over when one team remains")
+	}
+	result := engine.Result()
+	if result.Reason != EndLastTeamStanding {
+		t.Errorf("expected reason %q, got %q", EndLastTeamStanding, result.Reason)
+	}
+	if len(result.Winners) != 2 || result.Winners[0] != 1 || result.Winners[1] != 2 {
+		t.Errorf("expected whole team 1 to win, got %v", result.Winners)
+	}
+	if result.Players[2].Team != 2 {
+		t.Errorf("expected team in player results, got %+v", result.Players[2])
+	}
+}
+
+func TestTeams_ScanAlly(t *testing.T) {
+	cfg := config.Default()
+	engine := newTeamEngine(cfg)
+
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 bots east of P1, got %+v", results)
+	}
+	for i, wantAlly := range []bool{true, false, false} {
+		if results[i].Ally != wantAlly {
+			t.Errorf("result %d: expected ally=%v, got %+v", i, wantAlly, results[i])
+		}
+	}
+}
+
+func TestScan_ExtendedDetail(t *testing.T) {
+	cfg := config.Default()
+	engine := newTeamEngine(cfg)
+	engine.State.Players[1].HP = 30
+	engine.State.Players[1].GatherTicks = 2
+	engine.State.Players[2].Angle = 270
+
+	// Basic sensors report no details
+	for _, r := range CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20) {
+		if r.ScanDetail != nil {
+			t.Errorf("expected no detail with basic sensors, got %+v", r.ScanDetail)
+		}
+	}
+
+	cfg.Sensor.Detail = config.SensorExtended
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 90, 20)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 bots east of P1, got %+v", results)
+	}
+	want := []ScanDetail{
+		{ID: 2, HPBucket: 2, Heading: 90, Reloading: true},
+		{ID: 3, HPBucket: HPBuckets, Heading: 270},
+		{ID: 4, HPBucket: HPBuckets, Heading: 90},
+	}
+	for i, w := range want {
+		if results[i].ScanDetail == nil || *results[i].ScanDetail != w {
+			t.Errorf("result %d: expected %+v, got %+v", i, w, results[i].ScanDetail)
+		}
+	}
+}
+
+func TestEnergy_CostsAndRegen(t *testing.T) {
+	cfg := config.Default()
+	cfg.Match.RandomSeed = 1
+	cfg.Energy.Enabled = true
+	cfg.Energy.MaxEnergy = 30
+	cfg.Energy.RegenPerTick = 5
+	if got := NewGame(cfg, 2).State.Players[0].Energy; got != 30 {
+		t.Fatalf("expected full energy at spawn, got %v", got)
+	}
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Players[0].Energy = 30
+	startX := engine.State.Players[0].X
+
+	// move 10 (10) + turn 90 (9) + scan (2) = 21, then the toss (20) cannot be paid
+	engine.Update([][]Action{{
+		{Type: ActionMove, Value: 10},
+		{Type: ActionTurn, Value: 90},
+		{Type: ActionScan},
+		{Type: ActionToss, ThrowDistance: 50},
+	}, {}})
+
+	// Turns resolve before moves, so the bot moves east
+	p := engine.State.Players[0]
+	if p.X != startX+10 || p.Angle != 90 {
+		t.Errorf("expected move and turn to be applied, got %+v", p)
+	}
+	if len(engine.State.Snowballs) != 0 {
+		t.Errorf("expected the toss to be skipped, got %+v", engine.State.Snowballs)
+	}
+	if want := 30 - 21 + 5.0; math.Abs(p.Energy-want) > 1e-9 {
+		t.Errorf("expected energy %v after regeneration, got %v", want, p.Energy)
+	}
+
+	// Regeneration stops at the pool size
+	for i := 0; i < 5; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+	if got := engine.State.Players[0].Energy; got != 30 {
+		t.Errorf("expected energy capped at 30, got %v", got)
+	}
+}
+
+func TestTurn_RateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.MaxTurnPerTick = 30
+	engine := newEngineWithTwoPlayers(cfg)
+
+	engine.Update([][]Action{{{Type: ActionTurn, Value: 180}}, {{Type: ActionTurn, Value: -90}}})
+
+	if got := engine.State.Players[0].Angle; got != 30 {
+		t.Errorf("expected P1 angle 30, got %v", got)
+	}
+	// P2 starts facing south (180)
+	if got := engine.State.Players[1].Angle; got != 150 {
+		t.Errorf("expected P2 angle 150, got %v", got)
+	}
+}
+
+func TestInertia_Accelerates(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.Acceleration = 4
+	engine := newEngineWithTwoPlayers(cfg)
+	move := [][]Action{{{Type: ActionMove, Value: 10}}, {}}
+
+	// Speed ramps up 4, 8, 10 while move(10) is held
+	wantY := 0.0
+	for _, wantSpeed := range []float64{4, 8, 10} {
+		engine.Update(move)
+		wantY += wantSpeed
+		p := engine.State.Players[0]
+		if p.Speed != wantSpeed || p.Y != wantY {
+			t.Fatalf("expected speed %v at y=%v, got speed %v at y=%v", wantSpeed, wantY, p.Speed, p.Y)
+		}
+	}
+
+	// Without move() the bot slows down and coasts to a stop
+	for _, wantSpeed := range []float64{6, 2, 0, 0} {
+		engine.Update([][]Action{{}, {}})
+		wantY += wantSpeed
+		p := engine.State.Players[0]
+		if p.Speed != wantSpeed || p.Y != wantY {
+			t.Fatalf("expected speed %v at y=%v, got speed %v at y=%v", wantSpeed, wantY, p.Speed, p.Y)
+		}
+	}
+
+	// Gathering stops the bot at once
+	engine.Update(move)
+	engine.Update([][]Action{{{Type: ActionGather}}, {}})
+	if got := engine.State.Players[0].Speed; got != 0 {
+		t.Errorf("expected gathering to stop the bot, got speed %v", got)
+	}
+}
+
+func TestInertia_EnergyFollowsDisplacement(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.Acceleration = 4
+	cfg.Energy.Enabled = true
+	cfg.Energy.MaxEnergy = 100
+	cfg.Energy.RegenPerTick = 0
+	cfg.Energy.MoveCost = 1
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Players[0].Energy = 15
+
+	// move(10) is paid for by the distance travelled (4, then 8), not the requested speed
+	move := [][]Action{{{Type: ActionMove, Value: 10}}, {}}
+	for _, want := range []float64{11, 3} {
+		engine.Update(move)
+		if got := engine.State.Players[0].Energy; got != want {
+			t.Fatalf("expected energy %v, got %v", want, got)
+		}
+	}
+
+	// Slowing down still moves and costs energy; the speed is capped at what is left
+	engine.Update([][]Action{{}, {}})
+	p := engine.State.Players[0]
+	if p.Speed != 3 || p.Y != 15 || p.Energy != 0 {
+		t.Errorf("expected to coast 3 units on the last energy, got speed %v at y=%v with energy %v", p.Speed, p.Y, p.Energy)
+	}
+	engine.Update([][]Action{{}, {}})
+	if p := engine.State.Players[0]; p.Speed != 0 || p.Y != 15 {
+		t.Errorf("expected the bot to stop without energy, got speed %v at y=%v", p.Speed, p.Y)
+	}
+}
+
+func TestSnowball_RoundingDoesNotAccumulate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowball.Speed = 1
+	cfg.Field.PositionPrecision = 1
+	engine := newEngineWithTwoPlayers(cfg)
+	// Slow sideways drift: 0.04 per tick would never move if each tick were rounded on its own
+	engine.State.Snowballs = []Snowball{{ID: 1, OwnerID: 1, X: 0, Y: 200, VX: 0.04, VY: 1, Target: 100}}
+
+	for i := 0; i < 5; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+	sb := engine.State.Snowballs[0]
+	if sb.X != 0.2 || sb.Y != 205 {
+		t.Errorf("expected snowball at (0.2, 205), got (%v, %v)", sb.X, sb.Y)
+	}
+}
+
+func TestSnowball_NotRoundedAtPrecisionZero(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowball.Speed = 1
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Snowballs = []Snowball{{ID: 1, OwnerID: 1, X: 0, Y: 200, VX: 0.25, VY: 0.5, Target: 100}}
+
+	for i := 0; i < 3; i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+	sb := engine.State.Snowballs[0]
+	if sb.X != 0.75 || sb.Y != 201.5 {
+		t.Errorf("expected unrounded snowball at (0.75, 201.5), got (%v, %v)", sb.X, sb.Y)
+	}
+}
+
+func TestUpdate_SwappedPlayersMirror(t *testing.T) {
+	// Two bots with different call orders: one tosses before moving, the other after
+	tosser := []Action{{Type: ActionToss, ThrowDistance: 100}, {Type: ActionMove, Value: 10}, {Type: ActionTurn, Value: 20}}
+	mover := []Action{{Type: ActionTurn, Value: -20}, {Type: ActionMove, Value: 10}, {Type: ActionToss, ThrowDistance: 100}}
+	west := Player{X: -50, Y: 0, Angle: 90}
+	east := Player{X: 50, Y: 0, Angle: 270}
+
+	run := func(players []Player, actions [][]Action) *Engine {
+		cfg := config.Default()
+		cfg.Snowball.HitMode = config.HitModePath
+		cfg.Snowball.DamageRadius = 10
+		engine := newEngineWithTwoPlayers(cfg)
+		for i, p := range players {
+			p.HP = cfg.Snowbot.MaxHP
+			p.SnowballCount = cfg.Snowbot.MaxSnowball
+			engine.State.Players[i] = p
+		}
+		for tick := 0; tick < 20; tick++ {
+			engine.Update(actions)
+		}
+		return engine
+	}
+
+	a := run([]Player{west, east}, [][]Action{tosser, mover})
+	b := run([]Player{east, west}, [][]Action{mover, tosser})
+
+	for i := 0; i < 2; i++ {
+		if got, want := b.State.Players[1-i], a.State.Players[i]; got != want {
+			t.Errorf("player %d: expected mirrored state %+v, got %+v", i+1, want, got)
+		}
+	}
+	if len(a.State.Snowballs) != len(b.State.Snowballs) {
+		t.Fatalf("expected the same number of snowballs, got %d and %d", len(a.State.Snowballs), len(b.State.Snowballs))
+	}
+	for _, sa := range a.State.Snowballs {
+		found := false
+		for _, sb := range b.State.Snowballs {
+			if sb.OwnerID == 3-sa.OwnerID && sb.X == sa.X && sb.Y == sa.Y && sb.VX == sa.VX && sb.VY == sa.VY {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a mirrored snowball for %+v", sa)
+		}
+	}
+	ra, rb := a.Result(), b.Result()
+	if ra.Players[0].DamageReceived != rb.Players[1].DamageReceived || ra.Players[1].DamageReceived != rb.Players[0].DamageReceived {
+		t.Errorf("expected mirrored damage, got %+v and %+v", ra.Players, rb.Players)
+	}
+}
+
+func TestToss_Origin(t *testing.T) {
+	for _, tc := range []struct {
+		origin string
+		x, y   float64
+	}{
+		// Turned east and moved to (-40, 0) before throwing; the snowball then flies one step
+		{config.TossOriginPostMove, -30, 0},
+		// Thrown north from the start-of-tick position
+		{config.TossOriginPreMove, -50, 10},
+	} {
+		cfg := config.Default()
+		cfg.Snowball.TossOrigin = tc.origin
+		engine := newEngineWithTwoPlayers(cfg)
+
+		// The call order does not matter: the toss is listed first
+		engine.Update([][]Action{{
+			{Type: ActionToss, ThrowDistance: 50},
+			{Type: ActionMove, Value: 10},
+			{Type: ActionTurn, Value: 90},
+		}, {}})
+
+		if len(engine.State.Snowballs) != 1 {
+			t.Fatalf("%s: expected 1 snowball, got %d", tc.origin, len(engine.State.Snowballs))
+		}
+		if sb := engine.State.Snowballs[0]; math.Abs(sb.X-tc.x) > 1e-9 || math.Abs(sb.Y-tc.y) > 1e-9 {
+			t.Errorf("%s: expected snowball at (%v, %v), got (%v, %v)", tc.origin, tc.x, tc.y, sb.X, sb.Y)
+		}
+		if p := engine.State.P1; p.X != -40 || p.Y != 0 {
+			t.Errorf("%s: expected P1 at (-40, 0), got (%v, %v)", tc.origin, p.X, p.Y)
+		}
+	}
+}
+
+func TestNewGame_SpawnLayouts(t *testing.T) {
+	minDistance := func(players []Player) float64 {
+		nearest := math.Inf(1)
+		for i := range players {
+			for j := i + 1; j < len(players); j++ {
+				nearest = math.Min(nearest, math.Hypot(players[i].X-players[j].X, players[i].Y-players[j].Y))
+			}
+		}
+		return nearest
+	}
+
+	t.Run("random", func(t *testing.T) {
+		for seed := int64(1); seed <= 50; seed++ {
+			cfg := config.Default()
+			cfg.Match.MaxPlayers = 6
+			cfg.Match.RandomSeed = seed
+			if got := minDistance(NewGame(cfg, 6).State.Players); got < cfg.Match.MinSpawnDistance {
+				t.Fatalf("seed %d: players spawned %v apart", seed, got)
+			}
+		}
+	})
+
+	t.Run("circle", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Match.MaxPlayers = 4
+		cfg.Match.RandomSeed = 1
+		cfg.Match.Spawn = config.SpawnCircle
+		cfg.Field.PositionPrecision = 6
+		for i, p := range NewGame(cfg, 4).State.Players {
+			if r := math.Hypot(p.X, p.Y); math.Abs(r-400) > 1e-5 {
+				t.Errorf("player %d: expected radius 400, got %v", i+1, r)
+			}
+			// Facing the center
+			toCenter := normalizeAngle(math.Atan2(-p.X, -p.Y) * 180 / math.Pi)
+			if math.Abs(toCenter-p.Angle) > 1e-3 {
+				t.Errorf("player %d: expected angle %v towards the center, got %v", i+1, toCenter, p.Angle)
+			}
+		}
+	})
+
+	t.Run("circle around obstacles", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Match.MaxPlayers = 4
+		cfg.Field.Obstacles = []config.ObstacleConfig{
+			{Shape: config.ShapeRect, X: 0, Y: 400, Width: 400, Height: 100},
+			{Shape: config.ShapeCircle, X: 400, Y: 0, Radius: 150},
+		}
+		for seed := int64(1); seed <= 20; seed++ {
+			cfg.Match.RandomSeed = seed
+			engine := NewGame(cfg, 4)
+			for i, p := range engine.State.Players {
+				if insideObstacle(engine.Obstacles, p.X, p.Y) {
+					t.Fatalf("seed %d: player %d spawned inside an obstacle at (%v, %v)", seed, i+1, p.X, p.Y)
+				}
+			}
+		}
+	})
+
+	t.Run("random checks rounded positions", func(t *testing.T) {
+		// Small obstacles cover every integer point of a 2x2 field but (1, 1), so only
+		// positions that round to (1, 1) are clear at position_precision 0
+		cfg := config.Default()
+		cfg.Field.Width, cfg.Field.Height = 2, 2
+		var obstacles []Obstacle
+		for x := -1; x <= 1; x++ {
+			for y := -1; y <= 1; y++ {
+				if x != 1 || y != 1 {
+					obstacles = append(obstacles, Obstacle{Shape: config.ShapeCircle, X: float64(x), Y: float64(y), Radius: 0.3})
+				}
+			}
+		}
+		for seed := int64(1); seed <= 5; seed++ {
+			p := randomSpawn(cfg, obstacles, rand.New(rand.NewSource(seed)), nil, false)
+			if p.X != 1 || p.Y != 1 {
+				t.Errorf("seed %d: expected a spawn at (1, 1), got (%v, %v)", seed, p.X, p.Y)
+			}
+		}
+	})
+
+	t.Run("mirrored", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Match.MaxPlayers = 4
+		cfg.Match.RandomSeed = 7
+		cfg.Match.Spawn = config.SpawnMirrored
+		players := NewGame(cfg, 4).State.Players
+		for i := 0; i < 4; i += 2 {
+			a, b := players[i], players[i+1]
+			if b.X != -a.X || b.Y != -a.Y || b.Angle != normalizeAngle(a.Angle+180) {
+				t.Errorf("expected player %d to mirror player %d, got %+v and %+v", i+2, i+1, a, b)
+			}
+		}
+		if got := minDistance(players); got < cfg.Match.MinSpawnDistance {
+			t.Errorf("players spawned %v apart", got)
+		}
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Match.MaxPlayers = 3
+		cfg.Match.RandomSeed = 1
+		cfg.Match.Spawn = config.SpawnFixed
+		cfg.Match.SpawnPoints = []config.SpawnPointConfig{{X: -100, Y: 20, Angle: 90}, {X: 100, Y: -20, Angle: -90}}
+		players := NewGame(cfg, 3).State.Players
+		if p := players[0]; p.X != -100 || p.Y != 20 || p.Angle != 90 {
+			t.Errorf("expected player 1 at its spawn point, got %+v", p)
+		}
+		if p := players[1]; p.X != 100 || p.Y != -20 || p.Angle != 270 {
+			t.Errorf("expected player 2 at its spawn point facing 270, got %+v", p)
+		}
+		// The third player has no spawn point and is placed at random, away from the others
+		if got := minDistance(players); got < cfg.Match.MinSpawnDistance {
+			t.Errorf("players spawned %v apart", got)
+		}
+	})
+}
+
+func TestZone_ShrinksAndDamages(t *testing.T) {
+	cfg := config.Default()
+	cfg.Zone.Enabled = true
+	cfg.Zone.StartTick = 2
+	cfg.Zone.ShrinkPerTick = 400
+	cfg.Zone.FinalRadius = 100
+	cfg.Zone.Damage = 30
+	cfg.Zone.DamageInterval = 2
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Players[1].X = 300
+
+	if z := engine.State.Zone; z == nil || z.Radius != math.Hypot(500, 500) {
+		t.Fatalf("expected the zone to start at the field corners, got %+v", z)
+	}
+
+	wantRadius := []float64{math.Hypot(500, 500), math.Hypot(500, 500), math.Hypot(500, 500) - 400, 100, 100, 100}
+	wantHP := []int{100, 100, 100, 70, 70, 40}
+	for tick := 1; tick <= len(wantRadius); tick++ {
+		engine.Update([][]Action{{}, {}})
+		if got := engine.State.Zone.Radius; got != wantRadius[tick-1] {
+			t.Errorf("tick %d: expected radius %v, got %v", tick, wantRadius[tick-1], got)
+		}
+		if got := engine.State.Players[1].HP; got != wantHP[tick-1] {
+			t.Errorf("tick %d: expected P2 HP %d, got %d", tick, wantHP[tick-1], got)
+		}
+		if got := engine.State.Players[0].HP; got != cfg.Snowbot.MaxHP {
+			t.Errorf("tick %d: expected P1 inside the zone to keep full HP, got %d", tick, got)
+		}
+	}
+
+	// The zone eliminates P2 with no player to credit
+	for i := 0; i < 4 && engine.State.Players[1].Alive(); i++ {
+		engine.Update([][]Action{{}, {}})
+	}
+	if els := engine.Eliminations(); len(els) != 1 || els[0].PlayerID != 2 || els[0].By != 0 {
+		t.Errorf("expected P2 eliminated by the zone, got %+v", els)
+	}
+	if r := engine.Result(); r.Players[0].DamageDealt != 0 || r.Players[1].DamageReceived != 100 {
+		t.Errorf("expected zone damage received but not dealt, got %+v", r.Players)
+	}
+}
+
+func TestZone_Disabled(t *testing.T) {
+	engine := newEngineWithTwoPlayers(config.Default())
+	engine.Update([][]Action{{}, {}})
+	if engine.State.Zone != nil {
+		t.Errorf("expected no zone, got %+v", engine.State.Zone)
+	}
+}
+
+func TestPiles_SpawnAndCollect(t *testing.T) {
+	cfg := config.Default()
+	cfg.Piles.Enabled = true
+	cfg.Piles.Interval = 2
+	cfg.Piles.MaxPiles = 1
+	engine := newEngineWithTwoPlayers(cfg)
+
+	engine.Update([][]Action{{}, {}})
+	if len(engine.State.Piles) != 0 {
+		t.Fatalf("expected no pile before the interval, got %+v", engine.State.Piles)
+	}
+	engine.Update([][]Action{{}, {}})
+	engine.Update([][]Action{{}, {}})
+	engine.Update([][]Action{{}, {}})
+	if len(engine.State.Piles) != 1 || engine.State.Piles[0].Amount != cfg.Piles.Amount {
+		t.Fatalf("expected a single pile (max_piles = 1), got %+v", engine.State.Piles)
+	}
+
+	// Both players reach the pile: P1 moves 5 units from it and fills up first, P2 (10 units away) takes the rest
+	pile := engine.State.Piles[0]
+	engine.State.Players[0] = Player{X: pile.X, Y: pile.Y - 15, HP: 100, SnowballCount: cfg.Snowbot.MaxSnowball - 2}
+	engine.State.Players[1] = Player{X: pile.X + 10, Y: pile.Y, HP: 100, SnowballCount: cfg.Snowbot.MaxSnowball - 4}
+	snapshot := engine.State
+	engine.Update([][]Action{{{Type: ActionMove, Value: 10}}, {}})
+
+	if got := engine.State.Players[0].SnowballCount; got != cfg.Snowbot.MaxSnowball {
+		t.Errorf("expected the nearer P1 to fill up, got %d", got)
+	}
+	if got := engine.State.Players[1].SnowballCount; got != cfg.Snowbot.MaxSnowball-1 {
+		t.Errorf("expected P2 to take the remaining 3 snowballs, got %d", got)
+	}
+	if len(engine.State.Piles) != 0 {
+		t.Errorf("expected the emptied pile removed, got %+v", engine.State.Piles)
+	}
+	if snapshot.Piles[0].Amount != cfg.Piles.Amount {
+		t.Errorf("expected earlier state snapshots unchanged, got %+v", snapshot.Piles)
+	}
+}
+
+func TestPiles_Deterministic(t *testing.T) {
+	run := func() []Pile {
+		cfg := config.Default()
+		cfg.Match.RandomSeed = 42
+		cfg.Piles.Enabled = true
+		cfg.Piles.Interval = 1
+		engine := NewGame(cfg, 2)
+		for i := 0; i < 5; i++ {
+			engine.Update([][]Action{{}, {}})
+		}
+		return engine.State.Piles
+	}
+	a, b := run(), run()
+	if len(a) != 5 {
+		t.Fatalf("expected 5 piles, got %+v", a)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("expected the same piles for the same seed, got %+v and %+v", a[i], b[i])
+		}
+	}
+}
+
+func TestScan_Piles(t *testing.T) {
+	cfg := config.Default()
+	engine := newEngineWithTwoPlayers(cfg)
+	engine.State.Piles = []Pile{{ID: 1, X: -50, Y: 100, Amount: 3}}
+
+	results := CalculateScan(&engine.State, cfg, engine.Obstacles, 1, 0, 30)
+	if len(results) != 1 || results[0].Type != "pile" || results[0].Distance != 100 || results[0].Amount != 3 {
+		t.Errorf("expected the pile north of P1, got %+v", results)
+	}
+}
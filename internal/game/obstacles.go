@@ -0,0 +1,156 @@
+package game
+
+import (
+	"math"
+	"snowfight/internal/config"
+)
+
+// Obstacle is a static rectangle or circle on the field. X and Y are its center.
+type Obstacle struct {
+	Shape  string  `json:"shape"` // "rect" or "circle"
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+}
+
+// ObstaclesFromConfig converts the configured obstacles.
+func ObstaclesFromConfig(cfg *config.Config) []Obstacle {
+	obstacles := make([]Obstacle, len(cfg.Field.Obstacles))
+	for i, o := range cfg.Field.Obstacles {
+		obstacles[i] = Obstacle{
+			Shape:  o.Shape,
+			X:      o.X,
+			Y:      o.Y,
+			Width:  o.Width,
+			Height: o.Height,
+			Radius: o.Radius,
+		}
+	}
+	return obstacles
+}
+
+// Contains reports whether (x, y) lies inside the obstacle (boundary included).
+func (o Obstacle) Contains(x, y float64) bool {
+	switch o.Shape {
+	case config.ShapeRect:
+		return math.Abs(x-o.X) <= o.Width/2 && math.Abs(y-o.Y) <= o.Height/2
+	case config.ShapeCircle:
+		dx := x - o.X
+		dy := y - o.Y
+		return dx*dx+dy*dy <= o.Radius*o.Radius
+	}
+	return false
+}
+
+// nearestPoint returns the point of the obstacle closest to (x, y).
+func (o Obstacle) nearestPoint(x, y float64) (float64, float64) {
+	switch o.Shape {
+	case config.ShapeRect:
+		return math.Max(o.X-o.Width/2, math.Min(x, o.X+o.Width/2)), math.Max(o.Y-o.Height/2, math.Min(y, o.Y+o.Height/2))
+	case config.ShapeCircle:
+		dx := x - o.X
+		dy := y - o.Y
+		d := math.Sqrt(dx*dx + dy*dy)
+		if d <= o.Radius {
+			return x, y
+		}
+		return o.X + dx/d*o.Radius, o.Y + dy/d*o.Radius
+	}
+	return o.X, o.Y
+}
+
+// segmentEntry returns the fraction t in [0, 1] of the segment (x1,y1)-(x2,y2) at which it
+// enters the obstacle. A segment starting inside the obstacle does not enter it, so a bot
+// that somehow ends up inside can still move out.
+func (o Obstacle) segmentEntry(x1, y1, x2, y2 float64) (float64, bool) {
+	if o.Contains(x1, y1) {
+		return 0, false
+	}
+	dx := x2 - x1
+	dy := y2 - y1
+
+	switch o.Shape {
+	case config.ShapeRect:
+		// Slab method: intersect the parameter ranges for which the segment is within each axis.
+		tMin, tMax, ok := clipSlab(0, 1, x1, dx, o.X-o.Width/2, o.X+o.Width/2)
+		if ok {
+			tMin, tMax, ok = clipSlab(tMin, tMax, y1, dy, o.Y-o.Height/2, o.Y+o.Height/2)
+		}
+		if !ok {
+			return 0, false
+		}
+		return tMin, true
+	case config.ShapeCircle:
+		return segmentCircleEntry(x1, y1, x2, y2, o.X, o.Y, o.Radius)
+	}
+	return 0, false
+}
+
+// segmentCircleEntry returns the first fraction t in [0, 1] of the segment (x1,y1)-(x2,y2) that lies
+// within r of (cx, cy). A segment starting inside the circle enters it at t = 0.
+func segmentCircleEntry(x1, y1, x2, y2, cx, cy, r float64) (float64, bool) {
+	// Solve |start + t*d - center| = r for the smaller root.
+	fx := x1 - cx
+	fy := y1 - cy
+	c := fx*fx + fy*fy - r*r
+	if c <= 0 {
+		return 0, true
+	}
+	dx := x2 - x1
+	dy := y2 - y1
+	a := dx*dx + dy*dy
+	if a == 0 {
+		return 0, false
+	}
+	b := 2 * (fx*dx + fy*dy)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return 0, false
+	}
+	t := (-b - math.Sqrt(disc)) / (2 * a)
+	if t < 0 || t > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// clipSlab narrows [tMin, tMax] to the part of start+t*delta that lies within [lo, hi].
+func clipSlab(tMin, tMax, start, delta, lo, hi float64) (float64, float64, bool) {
+	if delta == 0 {
+		return tMin, tMax, start >= lo && start <= hi
+	}
+	t1 := (lo - start) / delta
+	t2 := (hi - start) / delta
+	if t1 > t2 {
+		t1, t2 = t2, t1
+	}
+	tMin = math.Max(tMin, t1)
+	tMax = math.Min(tMax, t2)
+	return tMin, tMax, tMin <= tMax
+}
+
+// firstObstacleHit returns the index of the first obstacle entered along the segment and the
+// fraction of the segment travelled before entering it.
+func firstObstacleHit(obstacles []Obstacle, x1, y1, x2, y2 float64) (int, float64, bool) {
+	hit := -1
+	best := 0.0
+	for i, o := range obstacles {
+		if t, ok := o.segmentEntry(x1, y1, x2, y2); ok && (hit < 0 || t < best) {
+			hit = i
+			best = t
+		}
+	}
+	return hit, best, hit >= 0
+}
+
+// insideObstacle reports whether (x, y) lies inside any obstacle.
+func insideObstacle(obstacles []Obstacle, x, y float64) bool {
+	for _, o := range obstacles {
+		if o.Contains(x, y) {
+			return true
+		}
+	}
+	return false
+}
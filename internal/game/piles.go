@@ -0,0 +1,78 @@
+package game
+
+import (
+	"math"
+	"sort"
+)
+
+// Pile is a heap of snowballs lying on the field when piles.enabled is set. Bots collect it
+// by moving within piles.pickup_radius of it.
+type Pile struct {
+	ID     int     `json:"id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Amount int     `json:"amount"` // Snowballs left in the pile
+}
+
+// collectPiles lets the players within piles.pickup_radius of a pile take its snowballs, up to
+// snowbot.max_snowball. When several players reach the same pile, the nearest takes first
+// (the lower player ID on a tie), and a pile that is not emptied stays on the field.
+func (e *Engine) collectPiles() {
+	if len(e.State.Piles) == 0 {
+		return
+	}
+	radius := e.Config.Piles.PickupRadius
+	// Build a new slice so earlier snapshots of the state are not modified
+	piles := make([]Pile, 0, len(e.State.Piles))
+	for _, pile := range e.State.Piles {
+		type collector struct {
+			index    int
+			distance float64
+		}
+		var collectors []collector
+		for i, p := range e.State.Players {
+			if d := math.Hypot(p.X-pile.X, p.Y-pile.Y); p.Alive() && d <= radius {
+				collectors = append(collectors, collector{i, d})
+			}
+		}
+		sort.SliceStable(collectors, func(a, b int) bool {
+			return collectors[a].distance < collectors[b].distance
+		})
+		for _, c := range collectors {
+			p := &e.State.Players[c.index]
+			taken := min(pile.Amount, e.Config.Snowbot.MaxSnowball-p.SnowballCount)
+			if taken <= 0 {
+				continue
+			}
+			p.SnowballCount += taken
+			pile.Amount -= taken
+		}
+		if pile.Amount > 0 {
+			piles = append(piles, pile)
+		}
+	}
+	e.State.Piles = piles
+}
+
+// spawnPile places a new pile every piles.interval ticks while fewer than piles.max_piles lie on
+// the field. The position is random, outside obstacles and inside the safe zone; no pile
+// appears when none is found within maxSpawnAttempts.
+func (e *Engine) spawnPile() {
+	cfg := e.Config.Piles
+	if !cfg.Enabled || e.State.Tick%cfg.Interval != 0 || len(e.State.Piles) >= cfg.MaxPiles {
+		return
+	}
+	halfWidth := float64(e.Config.Field.Width) / 2
+	halfHeight := float64(e.Config.Field.Height) / 2
+	precision := e.Config.Field.PositionPrecision
+	for attempt := 0; attempt < maxSpawnAttempts; attempt++ {
+		x := quantize(e.rng.Float64()*2*halfWidth-halfWidth, precision)
+		y := quantize(e.rng.Float64()*2*halfHeight-halfHeight, precision)
+		if insideObstacle(e.Obstacles, x, y) || (e.State.Zone != nil && !e.State.Zone.Contains(x, y)) {
+			continue
+		}
+		e.State.Piles = append(append([]Pile(nil), e.State.Piles...), Pile{ID: e.nextPileID, X: x, Y: y, Amount: cfg.Amount})
+		e.nextPileID++
+		return
+	}
+}
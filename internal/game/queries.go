@@ -7,72 +7,99 @@ import (
 )
 
 // CalculateScan performs the scan logic and returns detected objects.
-func CalculateScan(state *GameState, cfg *config.Config, playerID, angle, resolution int) []FieldObject {
-	// Normalize angle
-	angle = angle % 360
-	if angle < 0 {
-		angle += 360
-	}
-
-	// Check resolution range
-	if resolution < cfg.Sensor.MinScan || resolution > cfg.Sensor.MaxScan {
-		return []FieldObject{}
-	}
-
-	if resolution == 0 {
-		return []FieldObject{}
-	}
-
-	currentPlayer := state.PlayerRef(playerID)
-	if currentPlayer == nil {
+// Obstacles occlude what lies behind them and are reported themselves at the nearest point
+// of their surface seen within the cone.
+func CalculateScan(state *GameState, cfg *config.Config, obstacles []Obstacle, playerID, angle, resolution int) []FieldObject {
+	currentPlayer, angle, ok := scanOrigin(state, cfg, playerID, angle, resolution)
+	if !ok {
 		return []FieldObject{}
 	}
 
 	var results []FieldObject
 	for idx, other := range state.Players {
 		otherID := idx + 1
-		if otherID == playerID {
+		if otherID == playerID || !other.Alive() {
 			continue
 		}
 
-		dx := other.X - currentPlayer.X
-		dy := other.Y - currentPlayer.Y
-		dist := math.Sqrt(dx*dx + dy*dy)
+		enemyAngle, dist, ok := scanTarget(cfg, obstacles, currentPlayer, other.X, other.Y, angle, resolution)
+		if ok {
+			obj := FieldObject{
+				Type:     "snowbot",
+				Angle:    enemyAngle,
+				Distance: dist,
+				Ally:     state.Allies(playerID, otherID),
+			}
+			if cfg.Sensor.Detail == config.SensorExtended {
+				obj.ScanDetail = &ScanDetail{
+					ID:        otherID,
+					HPBucket:  hpBucket(other.HP, cfg.Snowbot.MaxHP),
+					Heading:   other.Angle,
+					Reloading: other.GatherTicks > 0,
+				}
+			}
+			results = append(results, obj)
+		}
+	}
 
-		// Angle to other (0° = north, 90° = east)
-		enemyAngle := math.Atan2(dx, dy) * 180 / math.Pi
-		if enemyAngle < 0 {
-			enemyAngle += 360
+	for _, pile := range state.Piles {
+		if pileAngle, dist, ok := scanTarget(cfg, obstacles, currentPlayer, pile.X, pile.Y, angle, resolution); ok {
+			results = append(results, FieldObject{
+				Type:     "pile",
+				Angle:    pileAngle,
+				Distance: dist,
+				Amount:   pile.Amount,
+			})
 		}
+	}
 
-		halfRes := float64(resolution) / 2.0
-		angleMin := float64(angle) - halfRes
-		angleMax := float64(angle) + halfRes
+	results = append(results, scanObstacles(cfg, obstacles, currentPlayer, angle, resolution)...)
 
-		if angleMin < 0 {
-			angleMin += 360
-		}
-		if angleMax >= 360 {
-			angleMax -= 360
+	// Sort by distance ascending, then angle ascending
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
 		}
+		return results[i].Angle < results[j].Angle
+	})
+
+	return results
+}
+
+// CalculateSnowballScan returns the flying snowballs of other players within the scan cone.
+// It uses the same cone and distance rules as CalculateScan.
+func CalculateSnowballScan(state *GameState, cfg *config.Config, obstacles []Obstacle, playerID, angle, resolution int) []SnowballObject {
+	if !cfg.Sensor.ScanSnowballs {
+		return []SnowballObject{}
+	}
+	currentPlayer, angle, ok := scanOrigin(state, cfg, playerID, angle, resolution)
+	if !ok {
+		return []SnowballObject{}
+	}
 
-		inRange := false
-		if angleMin <= angleMax {
-			inRange = enemyAngle >= angleMin && enemyAngle < angleMax
-		} else {
-			inRange = enemyAngle >= angleMin || enemyAngle < angleMax
+	var results []SnowballObject
+	for _, sb := range state.Snowballs {
+		if sb.OwnerID == playerID {
+			continue
 		}
 
-		if inRange && dist >= 1 {
-			maxDist := math.Sqrt(float64(cfg.Field.Width*cfg.Field.Width + cfg.Field.Height*cfg.Field.Height))
-			if dist <= maxDist {
-				results = append(results, FieldObject{
-					Type:     "snowbot",
-					Angle:    enemyAngle,
-					Distance: dist,
-				})
-			}
+		sbAngle, dist, ok := scanTarget(cfg, obstacles, currentPlayer, sb.X, sb.Y, angle, resolution)
+		if !ok {
+			continue
+		}
+		heading := math.Atan2(sb.VX, sb.VY) * 180 / math.Pi
+		if heading < 0 {
+			heading += 360
 		}
+		results = append(results, SnowballObject{
+			FieldObject: FieldObject{
+				Type:     "snowball",
+				Angle:    sbAngle,
+				Distance: dist,
+			},
+			Heading: heading,
+			Speed:   math.Sqrt(sb.VX*sb.VX + sb.VY*sb.VY),
+		})
 	}
 
 	// Sort by distance ascending, then angle ascending
@@ -85,3 +112,144 @@ func CalculateScan(state *GameState, cfg *config.Config, playerID, angle, resolu
 
 	return results
 }
+
+// obstacleRayStep is the spacing in degrees of the rays cast across the cone to find obstacle surfaces.
+const obstacleRayStep = 0.5
+
+// scanObstacles casts rays across the scan cone and reports, for every obstacle a ray hits first,
+// the nearest point of its surface. Besides evenly spaced rays it casts the cone edges and the
+// bearings to each obstacle's center and nearest point, so small and nearby obstacles are not missed.
+func scanObstacles(cfg *config.Config, obstacles []Obstacle, from *Player, angle, resolution int) []FieldObject {
+	if len(obstacles) == 0 {
+		return nil
+	}
+	halfRes := float64(resolution) / 2
+	bearings := []float64{float64(angle) - halfRes, float64(angle) + halfRes - 1e-6}
+	for a := float64(angle) - halfRes + obstacleRayStep; a < float64(angle)+halfRes; a += obstacleRayStep {
+		bearings = append(bearings, a)
+	}
+	for _, o := range obstacles {
+		nx, ny := o.nearestPoint(from.X, from.Y)
+		bearings = append(bearings,
+			math.Atan2(o.X-from.X, o.Y-from.Y)*180/math.Pi,
+			math.Atan2(nx-from.X, ny-from.Y)*180/math.Pi)
+	}
+
+	maxDist := math.Sqrt(float64(cfg.Field.Width*cfg.Field.Width + cfg.Field.Height*cfg.Field.Height))
+	nearest := make([]*FieldObject, len(obstacles))
+	for _, b := range bearings {
+		rad := b * math.Pi / 180
+		toX := from.X + math.Sin(rad)*maxDist
+		toY := from.Y + math.Cos(rad)*maxDist
+		hit, t, ok := firstObstacleHit(obstacles, from.X, from.Y, toX, toY)
+		if !ok {
+			continue
+		}
+		x := from.X + (toX-from.X)*t
+		y := from.Y + (toY-from.Y)*t
+		obstacleAngle, dist, ok := scanCone(cfg, from, x, y, angle, resolution)
+		if !ok {
+			continue
+		}
+		if nearest[hit] == nil || dist < nearest[hit].Distance {
+			nearest[hit] = &FieldObject{Type: "obstacle", Angle: obstacleAngle, Distance: dist}
+		}
+	}
+
+	var results []FieldObject
+	for _, obj := range nearest {
+		if obj != nil {
+			results = append(results, *obj)
+		}
+	}
+	return results
+}
+
+// hpBucket rounds hp up to a level from 1 to HPBuckets of maxHP.
+func hpBucket(hp, maxHP int) int {
+	if maxHP <= 0 || hp >= maxHP {
+		return HPBuckets
+	}
+	bucket := (hp*HPBuckets + maxHP - 1) / maxHP
+	if bucket < 1 {
+		return 1
+	}
+	return bucket
+}
+
+// scanOrigin validates the scan parameters and returns the scanning player and the normalized angle.
+func scanOrigin(state *GameState, cfg *config.Config, playerID, angle, resolution int) (*Player, int, bool) {
+	// Normalize angle
+	angle = angle % 360
+	if angle < 0 {
+		angle += 360
+	}
+
+	// Check resolution range
+	if resolution < cfg.Sensor.MinScan || resolution > cfg.Sensor.MaxScan {
+		return nil, 0, false
+	}
+
+	if resolution == 0 {
+		return nil, 0, false
+	}
+
+	currentPlayer := state.PlayerRef(playerID)
+	if currentPlayer == nil {
+		return nil, 0, false
+	}
+	return currentPlayer, angle, true
+}
+
+// scanTarget returns the bearing and distance from the scanner to (x, y) and whether it is
+// within the scan cone and not hidden behind an obstacle.
+func scanTarget(cfg *config.Config, obstacles []Obstacle, from *Player, x, y float64, angle, resolution int) (float64, float64, bool) {
+	targetAngle, dist, ok := scanCone(cfg, from, x, y, angle, resolution)
+	if !ok {
+		return 0, 0, false
+	}
+	if _, _, blocked := firstObstacleHit(obstacles, from.X, from.Y, x, y); blocked {
+		return 0, 0, false
+	}
+	return targetAngle, dist, true
+}
+
+// scanCone returns the bearing and distance from the scanner to (x, y) and whether it lies within the scan cone.
+func scanCone(cfg *config.Config, from *Player, x, y float64, angle, resolution int) (float64, float64, bool) {
+	dx := x - from.X
+	dy := y - from.Y
+	dist := math.Sqrt(dx*dx + dy*dy)
+
+	// Angle to target (0° = north, 90° = east)
+	targetAngle := math.Atan2(dx, dy) * 180 / math.Pi
+	if targetAngle < 0 {
+		targetAngle += 360
+	}
+
+	halfRes := float64(resolution) / 2.0
+	angleMin := float64(angle) - halfRes
+	angleMax := float64(angle) + halfRes
+
+	if angleMin < 0 {
+		angleMin += 360
+	}
+	if angleMax >= 360 {
+		angleMax -= 360
+	}
+
+	inRange := false
+	if angleMin <= angleMax {
+		inRange = targetAngle >= angleMin && targetAngle < angleMax
+	} else {
+		inRange = targetAngle >= angleMin || targetAngle < angleMax
+	}
+
+	if !inRange || dist < 1 {
+		return 0, 0, false
+	}
+	maxDist := math.Sqrt(float64(cfg.Field.Width*cfg.Field.Width + cfg.Field.Height*cfg.Field.Height))
+	if dist > maxDist {
+		return 0, 0, false
+	}
+	return targetAngle, dist, true
+}
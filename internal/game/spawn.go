@@ -0,0 +1,131 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+	"snowfight/internal/config"
+)
+
+// circleSpawnRatio is the radius of the circle layout relative to half the shorter field side.
+const circleSpawnRatio = 0.8
+
+// circleOffsetAttempts is the number of offsets tried for the circle layout before its radius is reduced.
+const circleOffsetAttempts = 10
+
+// spawnPoses returns the start pose of each player for the configured match.spawn layout.
+// Coordinates are rounded to field.position_precision before they are checked against
+// obstacles and spawn distances, so the checks hold for the positions the players start at.
+func spawnPoses(cfg *config.Config, obstacles []Obstacle, rng *rand.Rand, numPlayers int) []pose {
+	poses := make([]pose, 0, numPlayers)
+	switch cfg.Match.Spawn {
+	case config.SpawnCircle:
+		poses = circleSpawn(cfg, obstacles, rng, numPlayers)
+	case config.SpawnMirrored:
+		// Players come in pairs: the second of each pair is the first one reflected
+		// through the center of the field, facing the opposite way.
+		for len(poses) < numPlayers {
+			p := randomSpawn(cfg, obstacles, rng, poses, len(poses)+1 < numPlayers)
+			poses = append(poses, p)
+			if len(poses) < numPlayers {
+				poses = append(poses, pose{X: -p.X, Y: -p.Y, Angle: normalizeAngle(p.Angle + 180)})
+			}
+		}
+	case config.SpawnFixed:
+		for i := 0; i < numPlayers; i++ {
+			if i >= len(cfg.Match.SpawnPoints) {
+				// Players without a spawn point are placed at random
+				poses = append(poses, randomSpawn(cfg, obstacles, rng, poses, false))
+				continue
+			}
+			sp := cfg.Match.SpawnPoints[i]
+			x := quantize(sp.X, cfg.Field.PositionPrecision)
+			y := quantize(sp.Y, cfg.Field.PositionPrecision)
+			poses = append(poses, pose{X: x, Y: y, Angle: normalizeAngle(sp.Angle)})
+		}
+	default:
+		for i := 0; i < numPlayers; i++ {
+			poses = append(poses, randomSpawn(cfg, obstacles, rng, poses, false))
+		}
+	}
+	return poses
+}
+
+// circleSpawn places the players evenly on a circle around the field center, facing it. When
+// an obstacle covers one of the points, the circle is rotated to another random offset, and
+// after circleOffsetAttempts offsets it is shrunk by a tenth of its radius. If no layout
+// is clear of obstacles, the last one tried is used.
+func circleSpawn(cfg *config.Config, obstacles []Obstacle, rng *rand.Rand, numPlayers int) []pose {
+	halfSide := math.Min(float64(cfg.Field.Width), float64(cfg.Field.Height)) / 2
+	var poses []pose
+	for attempt := 0; attempt <= maxSpawnAttempts; attempt++ {
+		radius := halfSide * circleSpawnRatio * (1 - float64(attempt/circleOffsetAttempts)/10)
+		offset := rng.Float64() * 360
+		poses = poses[:0]
+		clear := true
+		for i := 0; i < numPlayers; i++ {
+			bearing := normalizeAngle(offset + float64(i)*360/float64(numPlayers))
+			rad := bearing * math.Pi / 180
+			x := quantize(math.Sin(rad)*radius, cfg.Field.PositionPrecision)
+			y := quantize(math.Cos(rad)*radius, cfg.Field.PositionPrecision)
+			clear = clear && !insideObstacle(obstacles, x, y)
+			// Face the center of the field
+			poses = append(poses, pose{X: x, Y: y, Angle: normalizeAngle(bearing + 180)})
+		}
+		if clear {
+			break
+		}
+	}
+	return poses
+}
+
+// randomSpawn picks a random pose outside obstacles and at least match.min_spawn_distance
+// from the players already placed (and, when mirrored, from its own reflection). If no
+// position qualifies within maxSpawnAttempts, the one farthest from the others is used.
+func randomSpawn(cfg *config.Config, obstacles []Obstacle, rng *rand.Rand, placed []pose, mirrored bool) pose {
+	halfWidth := float64(cfg.Field.Width) / 2
+	halfHeight := float64(cfg.Field.Height) / 2
+	minDistance := cfg.Match.MinSpawnDistance
+
+	var best, last pose
+	bestDistance := -1.0
+	for attempt := 0; attempt <= maxSpawnAttempts; attempt++ {
+		x := quantize(rng.Float64()*2*halfWidth-halfWidth, cfg.Field.PositionPrecision)
+		y := quantize(rng.Float64()*2*halfHeight-halfHeight, cfg.Field.PositionPrecision)
+		last = pose{X: x, Y: y}
+		if insideObstacle(obstacles, x, y) || (mirrored && insideObstacle(obstacles, -x, -y)) {
+			continue
+		}
+
+		nearest := math.Inf(1)
+		for _, p := range placed {
+			nearest = math.Min(nearest, math.Hypot(x-p.X, y-p.Y))
+			if mirrored {
+				nearest = math.Min(nearest, math.Hypot(-x-p.X, -y-p.Y))
+			}
+		}
+		if mirrored {
+			nearest = math.Min(nearest, 2*math.Hypot(x, y))
+		}
+		if nearest > bestDistance {
+			best, bestDistance = pose{X: x, Y: y}, nearest
+		}
+		if nearest >= minDistance {
+			break
+		}
+	}
+	if bestDistance < 0 {
+		// Obstacles cover every candidate
+		best = last
+	}
+	best.Angle = rng.Float64() * 360
+	return best
+}
+
+// normalizeAngle maps an angle in degrees to [0, 360).
+func normalizeAngle(angle float64) float64 {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	return angle
+}
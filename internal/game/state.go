@@ -10,6 +10,9 @@ type Snowball struct {
 	VY       float64 `json:"vy"`       // Velocity Y
 	Target   float64 `json:"target"`   // Target distance
 	Traveled float64 `json:"traveled"` // Distance traveled so far
+
+	// Difference between the exact position and the rounded X/Y, so rounding does not accumulate
+	roundingX, roundingY float64
 }
 
 // Player represents the state of a single player.
@@ -19,6 +22,24 @@ type Player struct {
 	HP            int     `json:"hp"`
 	Angle         float64 `json:"angle"` // In degrees
 	SnowballCount int     `json:"snowball_count"`
+	GatherTicks   int     `json:"gather_ticks,omitempty"`  // Remaining ticks of snowball gathering (0 = not gathering)
+	EliminatedAt  int     `json:"eliminated_at,omitempty"` // Tick at which HP reached 0 (0 = still in the match)
+	Team          int     `json:"team,omitempty"`          // Team number (0 = no team)
+	Energy        float64 `json:"energy,omitempty"`        // Remaining energy when energy.enabled is set
+	Speed         float64 `json:"speed,omitempty"`         // Current speed along the heading when snowbot.acceleration is set
+}
+
+// Alive reports whether the player is still in the match. Eliminated players do not act,
+// cannot be scanned and take no damage.
+func (p Player) Alive() bool {
+	return p.HP > 0
+}
+
+// Elimination records a player dropping out of the match.
+type Elimination struct {
+	Tick     int `json:"tick"`
+	PlayerID int `json:"player"` // 1-based player ID
+	By       int `json:"by"`     // 1-based ID of the player whose snowball made the final hit (0 = the zone)
 }
 
 // GameState represents the state of the game at a given tick.
@@ -29,6 +50,27 @@ type GameState struct {
 	P1        Player     `json:"p1,omitempty"`
 	P2        Player     `json:"p2,omitempty"`
 	Snowballs []Snowball `json:"snowballs"`
+	Zone      *Zone      `json:"zone,omitempty"`  // Safe zone when zone.enabled is set
+	Piles     []Pile     `json:"piles,omitempty"` // Snowball piles when piles.enabled is set
+}
+
+// Allies reports whether players a and b (1-based IDs) are distinct members of the same team.
+// Players without a team have no allies.
+func (s *GameState) Allies(a, b int) bool {
+	if a == b {
+		return false
+	}
+	pa := s.PlayerRef(a)
+	pb := s.PlayerRef(b)
+	return pa != nil && pb != nil && pa.Team > 0 && pa.Team == pb.Team
+}
+
+// side returns a key identifying the side player id (1-based) fights for: its team, or itself without one.
+func (s *GameState) side(id int) int {
+	if p := s.PlayerRef(id); p != nil && p.Team > 0 {
+		return p.Team
+	}
+	return -id
 }
 
 // PlayerRef returns pointer to player by 1-based ID, or nil.
@@ -56,6 +98,8 @@ const (
 	ActionMove
 	ActionTurn
 	ActionToss
+	ActionGather
+	ActionScan // Only spends energy; issued per scan call when energy.enabled is set
 )
 
 // Action represents an action returned by a player's script.
@@ -65,9 +109,60 @@ type Action struct {
 	ThrowDistance int     // Throw: target distance
 }
 
+// End reasons reported in Result.Reason.
+const (
+	EndLastBotStanding  = "last_bot_standing"
+	EndLastTeamStanding = "last_team_standing"
+	EndMaxTicks         = "max_ticks"
+	EndAllDead          = "all_dead"
+	EndError            = "error"
+)
+
+// PlayerResult summarizes a single player's performance in a finished match.
+type PlayerResult struct {
+	ID             int `json:"id"` // 1-based player ID
+	Team           int `json:"team,omitempty"`
+	HP             int `json:"hp"`
+	TicksSurvived  int `json:"ticks_survived"`
+	DamageDealt    int `json:"damage_dealt"` // Damage dealt to other players
+	DamageReceived int `json:"damage_received"`
+}
+
+// Result is the final outcome of a match.
+type Result struct {
+	Winners []int          `json:"winners"` // 1-based IDs; several winners means a draw, none means no winner
+	Reason  string         `json:"reason"`
+	Tick    int            `json:"tick"`
+	Seed    int64          `json:"seed"`
+	Players []PlayerResult `json:"players"`
+	Error   string         `json:"error,omitempty"`
+}
+
 // FieldObject represents an object detected by the scan API.
 type FieldObject struct {
-	Type     string  `json:"type"`     // "snowbot"
-	Angle    float64 `json:"angle"`    // Angle in degrees
-	Distance float64 `json:"distance"` // Distance from scanner
+	Type     string  `json:"type"`             // "snowbot", "obstacle", "snowball" or "pile"
+	Angle    float64 `json:"angle"`            // Angle in degrees
+	Distance float64 `json:"distance"`         // Distance from scanner
+	Ally     bool    `json:"ally,omitempty"`   // Detected snowbot is on the scanner's team
+	Amount   int     `json:"amount,omitempty"` // Snowballs in a detected pile
+	// Set for snowbots when sensor.detail is "extended"
+	*ScanDetail
+}
+
+// HPBuckets is the number of HP levels extended sensors distinguish.
+const HPBuckets = 4
+
+// ScanDetail is the extra information extended sensors report about a detected snowbot.
+type ScanDetail struct {
+	ID        int     `json:"id"`        // 1-based player ID
+	HPBucket  int     `json:"hp_bucket"` // HP in quarters of max HP, rounded up: 1 (almost down) to HPBuckets (full)
+	Heading   float64 `json:"heading"`   // Facing direction in degrees (0 = north)
+	Reloading bool    `json:"reloading"` // Making snowballs with make_snowball
+}
+
+// SnowballObject represents a flying snowball detected by the scan_snowballs API.
+type SnowballObject struct {
+	FieldObject
+	Heading float64 `json:"heading"` // Direction of travel in degrees (0 = north)
+	Speed   float64 `json:"speed"`   // Distance travelled per tick
 }
@@ -0,0 +1,48 @@
+package game
+
+import (
+	"math"
+	"snowfight/internal/config"
+)
+
+// Zone is the safe area of a match with zone.enabled: a circle around (X, Y). Bots outside it
+// take zone.damage every zone.damage_interval ticks.
+type Zone struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+// ZoneAt returns the safe zone at the given tick, or nil when the zone is disabled. The zone
+// starts as the circle through the field corners and shrinks by zone.shrink_per_tick every tick
+// after zone.start_tick until it reaches zone.final_radius.
+func ZoneAt(cfg *config.Config, tick int) *Zone {
+	if !cfg.Zone.Enabled {
+		return nil
+	}
+	radius := math.Hypot(float64(cfg.Field.Width)/2, float64(cfg.Field.Height)/2)
+	if tick > cfg.Zone.StartTick {
+		shrunk := radius - float64(tick-cfg.Zone.StartTick)*cfg.Zone.ShrinkPerTick
+		radius = math.Min(radius, math.Max(cfg.Zone.FinalRadius, shrunk))
+	}
+	return &Zone{Radius: radius}
+}
+
+// Contains reports whether (x, y) lies inside the zone (boundary included).
+func (z *Zone) Contains(x, y float64) bool {
+	return math.Hypot(x-z.X, y-z.Y) <= z.Radius
+}
+
+// applyZoneDamage damages the players outside the zone on every zone.damage_interval-th tick.
+func (e *Engine) applyZoneDamage() {
+	zone := e.State.Zone
+	if zone == nil || e.Config.Zone.Damage <= 0 || e.State.Tick%e.Config.Zone.DamageInterval != 0 {
+		return
+	}
+	for i := range e.State.Players {
+		p := &e.State.Players[i]
+		if p.Alive() && !zone.Contains(p.X, p.Y) {
+			e.damagePlayer(i, 0, e.Config.Zone.Damage)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package js
+
+import (
+	"encoding/json"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+)
+
+// maxWarningsPerTick caps the warnings a bot can emit in one tick.
+const maxWarningsPerTick = 3
+
+// API implements the SnowBot API independently of the script language. Runtimes decode
+// script calls and forward them here, so every backend applies the same rules, limits and
+// warnings. Args passed to the methods are only used to describe the call in warnings.
+type API struct {
+	Config   *config.Config
+	PlayerID int // 1-based player ID
+
+	state     *game.GameState
+	obstacles []game.Obstacle // converted once from the config for scans
+	actions   []game.Action
+
+	// per-tick guards to prevent multiple calls of the same API
+	used map[string]bool
+
+	inbox     []RadioMessage // delivered for the current tick
+	outbox    []RadioMessage // sent during the current tick
+	radioUsed int            // bytes sent during the current tick
+
+	storage        json.RawMessage // value kept across matches, nil when empty
+	storageEnabled bool
+
+	energyLeft float64 // energy left during the current tick
+
+	warnings []Warning
+}
+
+// ZoneInfo is the safe zone as returned by zone().
+type ZoneInfo struct {
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Radius        float64 `json:"radius"`
+	FinalRadius   float64 `json:"final_radius"`
+	ShrinkPerTick float64 `json:"shrink_per_tick"`
+	StartTick     int     `json:"start_tick"`
+}
+
+// Received is a radio message as returned by receive().
+type Received struct {
+	From    int             `json:"from"`
+	Message json.RawMessage `json:"message"`
+}
+
+// NewAPI creates the API state of one bot.
+func NewAPI(cfg *config.Config, playerID int) *API {
+	return &API{
+		Config:    cfg,
+		PlayerID:  playerID,
+		obstacles: game.ObstaclesFromConfig(cfg),
+		used:      map[string]bool{},
+	}
+}
+
+// BeginTick resets the per-tick guards, outbox, energy and warnings for a Run with state.
+func (a *API) BeginTick(state *game.GameState) {
+	// Ensure Players slice is populated for scripts even if legacy fields were set.
+	if len(state.Players) == 0 {
+		legacy := []game.Player{}
+		if state.P1 != (game.Player{}) {
+			legacy = append(legacy, state.P1)
+		}
+		if state.P2 != (game.Player{}) {
+			legacy = append(legacy, state.P2)
+		}
+		state.Players = legacy
+	}
+
+	a.state = state
+	a.actions = nil
+	a.used = map[string]bool{}
+	a.outbox = nil
+	a.radioUsed = 0
+	a.warnings = nil
+	a.energyLeft = 0
+	if player := a.Player(); player != nil && a.Config.Energy.Enabled {
+		a.energyLeft = player.Energy
+	}
+}
+
+// Actions returns the actions issued during the current tick.
+func (a *API) Actions() []game.Action {
+	return a.actions
+}
+
+// Warnings returns the warnings emitted during the current tick.
+func (a *API) Warnings() []Warning {
+	return a.warnings
+}
+
+// Player returns the bot's own player in the current state, or nil outside a tick.
+func (a *API) Player() *game.Player {
+	if a.state == nil {
+		return nil
+	}
+	return a.state.PlayerRef(a.PlayerID)
+}
+
+// Tick returns the tick of the current state.
+func (a *API) Tick() int {
+	if a.state == nil {
+		return 0
+	}
+	return a.state.Tick
+}
+
+// Warn records a warning for a call of api (at most maxWarningsPerTick per tick).
+func (a *API) Warn(msg, api string, args []interface{}) {
+	if len(a.warnings) >= maxWarningsPerTick {
+		return
+	}
+	a.warnings = append(a.warnings, Warning{
+		Warning: msg,
+		Tick:    a.Tick(),
+		Player:  a.PlayerID,
+		API:     api,
+		Args:    args,
+	})
+}
+
+// WarnTimeout records an "execution timed out" warning along with the budget usage.
+func (a *API) WarnTimeout(limit string, used, budget int) {
+	if len(a.warnings) >= maxWarningsPerTick {
+		return
+	}
+	a.warnings = append(a.warnings, Warning{
+		Warning:           "execution timed out",
+		Tick:              a.Tick(),
+		Player:            a.PlayerID,
+		API:               "run",
+		Limit:             limit,
+		InstructionsUsed:  used,
+		InstructionBudget: budget,
+	})
+}
+
+// FirstCall reports whether api is called for the first time this tick. Later calls are
+// ignored with a warning.
+func (a *API) FirstCall(api string, args []interface{}) bool {
+	if a.used[api] {
+		a.Warn("called multiple times in one tick", api, args)
+		return false
+	}
+	a.used[api] = true
+	return true
+}
+
+// Move issues a move, clamping |distance| to [min_move, max_move]. 0 is a no-op.
+func (a *API) Move(distance int, args []interface{}) {
+	if distance == 0 {
+		return
+	}
+
+	// Clamp to MIN_MOVE <= |distance| <= MAX_MOVE
+	if distance > 0 {
+		if distance < a.Config.Snowbot.MinMove {
+			distance = a.Config.Snowbot.MinMove
+		} else if distance > a.Config.Snowbot.MaxMove {
+			distance = a.Config.Snowbot.MaxMove
+		}
+	} else {
+		if distance > -a.Config.Snowbot.MinMove {
+			distance = -a.Config.Snowbot.MinMove
+		} else if distance < -a.Config.Snowbot.MaxMove {
+			distance = -a.Config.Snowbot.MaxMove
+		}
+	}
+
+	action := game.Action{Type: game.ActionMove, Value: float64(distance)}
+	if a.Config.Snowbot.Acceleration > 0 {
+		// With inertia the engine charges the distance actually travelled at the end of the tick
+		a.actions = append(a.actions, action)
+		return
+	}
+	a.issue(action, "move", args)
+}
+
+// Turn issues a turn, clamping |angle| to max_turn_per_tick when set. 0 is a no-op.
+func (a *API) Turn(angle int, args []interface{}) {
+	if angle == 0 {
+		return
+	}
+
+	// Clamp to |angle| <= MAX_TURN_PER_TICK
+	if limit := a.Config.Snowbot.MaxTurnPerTick; limit > 0 && (angle > limit || angle < -limit) {
+		a.Warn("clamped to max_turn_per_tick", "turn", args)
+		if angle > 0 {
+			angle = limit
+		} else {
+			angle = -limit
+		}
+	}
+
+	a.issue(game.Action{Type: game.ActionTurn, Value: float64(angle)}, "turn", args)
+}
+
+// Toss throws a snowball distance ahead, up to max_flying_distance. Distances <= 0 are a no-op.
+func (a *API) Toss(distance int, args []interface{}) {
+	if distance <= 0 {
+		return
+	}
+	if distance > a.Config.Snowball.MaxFlyingDistance {
+		distance = a.Config.Snowball.MaxFlyingDistance
+	}
+
+	a.issue(game.Action{Type: game.ActionToss, ThrowDistance: distance}, "toss", args)
+}
+
+// MakeSnowball starts gathering snowballs.
+func (a *API) MakeSnowball(args []interface{}) {
+	if player := a.Player(); player != nil && player.GatherTicks > 0 {
+		a.Warn("already making snowballs", "make_snowball", args)
+		return
+	}
+	a.actions = append(a.actions, game.Action{Type: game.ActionGather})
+}
+
+// Scan returns the objects detected in the scan cone.
+func (a *API) Scan(angle, resolution int, args []interface{}) []game.FieldObject {
+	if a.state == nil || !a.spendScanEnergy("scan", args) {
+		return nil
+	}
+	return game.CalculateScan(a.state, a.Config, a.obstacles, a.PlayerID, angle, resolution)
+}
+
+// ScanSnowballs returns the flying snowballs of other bots detected in the scan cone.
+func (a *API) ScanSnowballs(angle, resolution int, args []interface{}) []game.SnowballObject {
+	if !a.Config.Sensor.ScanSnowballs {
+		a.Warn("disabled by config", "scan_snowballs", args)
+		return nil
+	}
+	if a.state == nil || !a.spendScanEnergy("scan_snowballs", args) {
+		return nil
+	}
+	return game.CalculateSnowballScan(a.state, a.Config, a.obstacles, a.PlayerID, angle, resolution)
+}
+
+// Send queues a radio message. message is the JSON encoding of the script value, empty or
+// "undefined" when the value cannot be serialized.
+func (a *API) Send(channel, message string, args []interface{}) {
+	if a.Config.Radio.MaxBytesPerTick <= 0 {
+		a.Warn("disabled by config", "send", args)
+		return
+	}
+
+	if message == "" || message == "undefined" || !json.Valid([]byte(message)) {
+		a.Warn("message is not JSON-serializable", "send", args)
+		return
+	}
+
+	size := len(channel) + len(message)
+	if a.Config.Radio.MaxMessageBytes > 0 && size > a.Config.Radio.MaxMessageBytes {
+		a.Warn("message too large", "send", args)
+		return
+	}
+	if a.radioUsed+size > a.Config.Radio.MaxBytesPerTick {
+		a.Warn("bandwidth exceeded", "send", args)
+		return
+	}
+
+	a.radioUsed += size
+	a.outbox = append(a.outbox, RadioMessage{
+		From:    a.PlayerID,
+		Channel: channel,
+		Message: json.RawMessage(message),
+	})
+}
+
+// Receive returns the messages delivered on channel for this tick.
+func (a *API) Receive(channel string) []Received {
+	results := []Received{}
+	for _, m := range a.inbox {
+		if m.Channel == channel {
+			results = append(results, Received{From: m.From, Message: m.Message})
+		}
+	}
+	return results
+}
+
+// Deliver sets the radio messages Receive returns during the next tick.
+func (a *API) Deliver(messages []RadioMessage) {
+	a.inbox = messages
+}
+
+// Sent returns the radio messages sent during the current tick.
+func (a *API) Sent() []RadioMessage {
+	return a.outbox
+}
+
+// SetStorage enables storage.get/set with the given stored value (nil for none).
+func (a *API) SetStorage(data json.RawMessage) {
+	a.storageEnabled = true
+	a.storage = nil
+	if len(data) > 0 && string(data) != "null" {
+		a.storage = data
+	}
+}
+
+// Storage returns the value stored with storage.set, or nil if there is none.
+func (a *API) Storage() json.RawMessage {
+	return a.storage
+}
+
+// StorageSet stores value, the JSON encoding of the script value ("null" clears the storage).
+func (a *API) StorageSet(value string, args []interface{}) {
+	if !a.storageEnabled {
+		a.Warn("storage not enabled for this match", "storage.set", args)
+		return
+	}
+	if a.Config.Storage.MaxBytes <= 0 {
+		a.Warn("disabled by config", "storage.set", args)
+		return
+	}
+
+	if value == "" || value == "undefined" || !json.Valid([]byte(value)) {
+		a.Warn("value is not JSON-serializable", "storage.set", args)
+		return
+	}
+	if len(value) > a.Config.Storage.MaxBytes {
+		a.Warn("value too large", "storage.set", args)
+		return
+	}
+
+	if value == "null" {
+		a.storage = nil
+	} else {
+		a.storage = json.RawMessage(value)
+	}
+}
+
+// Energy returns the energy left this tick (0 when the energy economy is disabled).
+func (a *API) Energy() float64 {
+	return a.energyLeft
+}
+
+// MaxEnergy returns the energy pool size (0 when the energy economy is disabled).
+func (a *API) MaxEnergy() float64 {
+	if !a.Config.Energy.Enabled {
+		return 0
+	}
+	return a.Config.Energy.MaxEnergy
+}
+
+// Zone returns the safe zone of the current tick, or nil when zone.enabled is not set.
+func (a *API) Zone() *ZoneInfo {
+	zone := game.ZoneAt(a.Config, a.Tick())
+	if zone == nil {
+		return nil
+	}
+	return &ZoneInfo{
+		X:             zone.X,
+		Y:             zone.Y,
+		Radius:        zone.Radius,
+		FinalRadius:   a.Config.Zone.FinalRadius,
+		ShrinkPerTick: a.Config.Zone.ShrinkPerTick,
+		StartTick:     a.Config.Zone.StartTick,
+	}
+}
+
+// issue records an action once its energy cost is paid.
+func (a *API) issue(action game.Action, api string, args []interface{}) {
+	if a.spendEnergy(action, api, args) {
+		a.actions = append(a.actions, action)
+	}
+}
+
+// spendEnergy reserves the energy an action costs this tick, warning when the bot cannot pay it.
+// The engine charges the actions by phase rather than in call order, but the cost of each action
+// does not depend on the others, so the total stays within the energy reserved here in any order.
+// The engine only ever charges less (clamped turns, skipped moves and tosses), so every reserved
+// action it applies can still be paid. Moves with inertia are not reserved: the engine charges the
+// distance travelled after the other actions and limits the speed to the energy that is left.
+func (a *API) spendEnergy(action game.Action, api string, args []interface{}) bool {
+	cost := game.ActionCost(a.Config, action)
+	if cost <= 0 {
+		return true
+	}
+	if cost > a.energyLeft {
+		a.Warn("not enough energy", api, args)
+		return false
+	}
+	a.energyLeft -= cost
+	return true
+}
+
+// spendScanEnergy pays for a scan call and records it as an action so the engine deducts it too.
+func (a *API) spendScanEnergy(api string, args []interface{}) bool {
+	action := game.Action{Type: game.ActionScan}
+	if !a.spendEnergy(action, api, args) {
+		return false
+	}
+	if a.Config.Energy.Enabled {
+		a.actions = append(a.actions, action)
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package js
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"snowfight/internal/config"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a runtime for the given 1-based player.
+type Factory func(cfg *config.Config, playerID int) Runtime
+
+func newQuickJS(cfg *config.Config, playerID int) Runtime {
+	return NewQuickJSRuntime(cfg, playerID)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{".js": newQuickJS}
+	schemes    = map[string]Factory{"js": newQuickJS}
+)
+
+// Register makes a runtime available for bot files with the given extension (e.g. ".wasm").
+// Registering an extension again replaces the previous factory.
+func Register(ext string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(ext)] = factory
+}
+
+// RegisterScheme makes a runtime available for bot specs of the form "<scheme>:<target>"
+// (e.g. "js:bot.txt"), which select the runtime regardless of the file extension.
+func RegisterScheme(scheme string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	schemes[scheme] = factory
+}
+
+// SplitSpec splits a bot spec into its registered scheme and target. Specs without a
+// registered scheme, such as paths and http(s) URLs, return an empty scheme and the spec.
+func SplitSpec(spec string) (scheme, target string) {
+	prefix, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return "", spec
+	}
+	registryMu.RLock()
+	_, registered := schemes[prefix]
+	registryMu.RUnlock()
+	if !registered {
+		return "", spec
+	}
+	return prefix, rest
+}
+
+// NewRuntime creates the runtime for a bot spec: the runtime of its scheme if it has one,
+// otherwise the runtime registered for the extension of the bot file. Files with an
+// unregistered or no extension, such as raw gist URLs, run on QuickJS.
+func NewRuntime(spec string, cfg *config.Config, playerID int) (Runtime, error) {
+	scheme, file := SplitSpec(spec)
+	registryMu.RLock()
+	factory, ok := schemes[scheme]
+	if scheme == "" {
+		factory, ok = registry[botExt(file)]
+		if !ok {
+			factory, ok = registry[".js"]
+		}
+	}
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported bot file %s (supported: %s)", file, strings.Join(Extensions(), ", "))
+	}
+	return factory(cfg, playerID), nil
+}
+
+// botExt returns the lower-cased extension of a bot file. For http(s) URLs it is taken
+// from the URL path, so a query string such as "?token=x" is not part of it.
+func botExt(file string) string {
+	if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+		if u, err := url.Parse(file); err == nil {
+			return strings.ToLower(path.Ext(u.Path))
+		}
+	}
+	return strings.ToLower(filepath.Ext(file))
+}
+
+// Extensions returns the registered bot file extensions in sorted order.
+func Extensions() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	exts := make([]string, 0, len(registry))
+	for ext := range registry {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
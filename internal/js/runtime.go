@@ -11,28 +11,38 @@ import (
 	"github.com/buke/quickjs-go"
 )
 
+// instructionsPerInterrupt is the number of bytecode branch/call operations QuickJS
+// executes between interrupt handler invocations (JS_INTERRUPT_COUNTER_INIT).
+const instructionsPerInterrupt = 10000
+
 // Runtime defines the interface for a game script runtime.
 type Runtime interface {
 	Load(code string) error
 	Run(state game.GameState) ([]game.Action, []Warning, error)
+	// Deliver sets the radio messages receive() returns during the next Run.
+	Deliver(messages []RadioMessage)
+	// Sent returns the radio messages sent during the last Run.
+	Sent() []RadioMessage
+	// SetStorage enables storage.get/set with the given stored value. Call it before Load.
+	SetStorage(data json.RawMessage)
+	// Storage returns the value stored with storage.set.
+	Storage() json.RawMessage
 	Close()
 }
 
+// RadioMessage is a message sent with send() and delivered to other bots one tick later.
+type RadioMessage struct {
+	From    int             `json:"from"` // 1-based sender ID
+	Channel string          `json:"channel"`
+	Message json.RawMessage `json:"message"` // JSON-encoded message
+}
+
 // QuickJSRuntime implements Runtime using github.com/buke/quickjs-go (CGO-based QuickJS).
 type QuickJSRuntime struct {
-	rt             *quickjs.Runtime
-	ctx            *quickjs.Context
-	currentActions []game.Action
-	currentState   *game.GameState
-	playerID       int // 1-based player ID
-	Config         *config.Config
-
-	// per-tick guards to prevent multiple calls of the same API
-	moveUsed bool
-	turnUsed bool
-	tossUsed bool
-
-	warnings []Warning
+	rt     *quickjs.Runtime
+	ctx    *quickjs.Context
+	api    *API
+	Config *config.Config
 }
 
 // Warning represents an API misuse warning to emit as JSONL.
@@ -42,8 +52,19 @@ type Warning struct {
 	Player  int           `json:"player"` // 1-based
 	API     string        `json:"api"`
 	Args    []interface{} `json:"args,omitempty"`
+
+	// Limit, InstructionsUsed and InstructionBudget describe which limit stopped a timed out tick.
+	Limit             string `json:"limit,omitempty"` // "instructions" or "wall_clock"
+	InstructionsUsed  int    `json:"instructions_used,omitempty"`
+	InstructionBudget int    `json:"instruction_budget,omitempty"`
 }
 
+// Limit values reported on "execution timed out" warnings.
+const (
+	LimitInstructions = "instructions"
+	LimitWallClock    = "wall_clock"
+)
+
 // ScriptState is the per-player state exposed to bot scripts.
 type ScriptState struct {
 	Tick          int     `json:"tick"`
@@ -52,6 +73,9 @@ type ScriptState struct {
 	Angle         float64 `json:"angle"`
 	HP            int     `json:"hp"`
 	SnowballCount int     `json:"snowball_count"`
+	GatherTicks   int     `json:"gather_ticks"`
+	Team          int     `json:"team"`
+	Speed         float64 `json:"speed"`
 }
 
 // NewQuickJSRuntime creates a new QuickJSRuntime instance.
@@ -64,10 +88,10 @@ func NewQuickJSRuntime(cfg *config.Config, playerID int) *QuickJSRuntime {
 	ctx := rt.NewContext()
 
 	qjsRt := &QuickJSRuntime{
-		rt:       rt,
-		ctx:      ctx,
-		playerID: playerID,
-		Config:   cfg,
+		rt:     rt,
+		ctx:    ctx,
+		api:    NewAPI(cfg, playerID),
+		Config: cfg,
 	}
 	qjsRt.registerBuiltins()
 	return qjsRt
@@ -83,136 +107,109 @@ func (rt *QuickJSRuntime) Close() {
 	}
 }
 
+// Deliver sets the radio messages receive() returns during the next Run.
+func (rt *QuickJSRuntime) Deliver(messages []RadioMessage) {
+	rt.api.Deliver(messages)
+}
+
+// Sent returns the radio messages sent during the last Run.
+func (rt *QuickJSRuntime) Sent() []RadioMessage {
+	return rt.api.Sent()
+}
+
+// SetStorage enables storage.get/set with the given stored value (nil for none).
+// Call it before Load so the top-level code of the script can read it.
+func (rt *QuickJSRuntime) SetStorage(data json.RawMessage) {
+	rt.api.SetStorage(data)
+}
+
+// Storage returns the value stored with storage.set, or nil if there is none.
+func (rt *QuickJSRuntime) Storage() json.RawMessage {
+	return rt.api.Storage()
+}
+
 func (rt *QuickJSRuntime) registerBuiltins() {
 	globals := rt.ctx.Globals()
+	api := rt.api
 
 	// move(distance)
 	globals.Set("move", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.moveUsed {
-			rt.addWarning("called multiple times in one tick", "move", args)
+		if !api.FirstCall("move", warningArgs(args)) {
 			return ctx.NewNull()
 		}
-		rt.moveUsed = true
-
 		if len(args) == 0 {
-			rt.addWarning("missing argument", "move", args)
-			return ctx.NewNull()
-		}
-
-		distance := int(args[0].ToFloat64())
-
-		// No-op if distance is 0
-		if distance == 0 {
+			api.Warn("missing argument", "move", warningArgs(args))
 			return ctx.NewNull()
 		}
-
-		// Clamp to MIN_MOVE <= |distance| <= MAX_MOVE
-		if distance > 0 {
-			if distance < rt.Config.Snowbot.MinMove {
-				distance = rt.Config.Snowbot.MinMove
-			} else if distance > rt.Config.Snowbot.MaxMove {
-				distance = rt.Config.Snowbot.MaxMove
-			}
-		} else {
-			if distance > -rt.Config.Snowbot.MinMove {
-				distance = -rt.Config.Snowbot.MinMove
-			} else if distance < -rt.Config.Snowbot.MaxMove {
-				distance = -rt.Config.Snowbot.MaxMove
-			}
-		}
-
-		rt.currentActions = append(rt.currentActions, game.Action{
-			Type:  game.ActionMove,
-			Value: float64(distance),
-		})
+		api.Move(int(args[0].ToFloat64()), warningArgs(args))
 		return ctx.NewNull()
 	}))
 
 	// turn(degrees)
 	globals.Set("turn", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.turnUsed {
-			rt.addWarning("called multiple times in one tick", "turn", args)
+		if !api.FirstCall("turn", warningArgs(args)) {
 			return ctx.NewNull()
 		}
-		rt.turnUsed = true
-
 		if len(args) == 0 {
-			rt.addWarning("missing argument", "turn", args)
+			api.Warn("missing argument", "turn", warningArgs(args))
 			return ctx.NewNull()
 		}
-
-		angle := int(args[0].ToFloat64())
-		if angle == 0 {
-			return ctx.NewNull()
-		}
-
-		rt.currentActions = append(rt.currentActions, game.Action{
-			Type:  game.ActionTurn,
-			Value: float64(angle),
-		})
+		api.Turn(int(args[0].ToFloat64()), warningArgs(args))
 		return ctx.NewNull()
 	}))
 
 	// console.log
 	globals.Set("console_log", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		var printArgs []interface{}
-		for _, arg := range args {
-			printArgs = append(printArgs, arg.String())
-		}
-		fmt.Fprintln(os.Stderr, printArgs...)
+		fmt.Fprintln(os.Stderr, warningArgs(args)...)
 		return ctx.NewNull()
 	}))
 
 	// toss(distance)
 	globals.Set("toss", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.tossUsed {
-			rt.addWarning("called multiple times in one tick", "toss", args)
+		if !api.FirstCall("toss", warningArgs(args)) {
 			return ctx.NewNull()
 		}
-		rt.tossUsed = true
-
 		if len(args) < 1 {
-			rt.addWarning("missing argument", "toss", args)
+			api.Warn("missing argument", "toss", warningArgs(args))
 			return ctx.NewNull()
 		}
+		api.Toss(int(args[0].ToFloat64()), warningArgs(args))
+		return ctx.NewNull()
+	}))
 
-		distance := int(args[0].ToFloat64())
-
-		// Handle negative distance
-		if distance < 0 {
-			distance = 0
-		}
-
-		if distance == 0 {
+	// make_snowball()
+	globals.Set("make_snowball", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if !api.FirstCall("make_snowball", warningArgs(args)) {
 			return ctx.NewNull()
 		}
-
-		if distance > rt.Config.Snowball.MaxFlyingDistance {
-			distance = rt.Config.Snowball.MaxFlyingDistance
-		}
-
-		rt.currentActions = append(rt.currentActions, game.Action{
-			Type:          game.ActionToss,
-			ThrowDistance: distance,
-		})
+		api.MakeSnowball(warningArgs(args))
 		return ctx.NewNull()
 	}))
 
 	// scan(angle, resolution)
 	globals.Set("scan", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
 		if len(args) < 2 {
-			rt.addWarning("missing argument", "scan", args)
+			api.Warn("missing argument", "scan", warningArgs(args))
 			return ctx.ParseJSON("[]")
 		}
 
-		if rt.currentState == nil {
+		results := api.Scan(int(args[0].ToFloat64()), int(args[1].ToFloat64()), warningArgs(args))
+		if len(results) == 0 {
 			return ctx.ParseJSON("[]")
 		}
 
-		angle := int(args[0].ToFloat64())
-		resolution := int(args[1].ToFloat64())
+		resultsJSON, _ := json.Marshal(results)
+		return ctx.ParseJSON(string(resultsJSON))
+	}))
 
-		results := game.CalculateScan(rt.currentState, rt.Config, rt.playerID, angle, resolution)
+	// scan_snowballs(angle, resolution)
+	globals.Set("scan_snowballs", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if len(args) < 2 {
+			api.Warn("missing argument", "scan_snowballs", warningArgs(args))
+			return ctx.ParseJSON("[]")
+		}
+
+		results := api.ScanSnowballs(int(args[0].ToFloat64()), int(args[1].ToFloat64()), warningArgs(args))
 		if len(results) == 0 {
 			return ctx.ParseJSON("[]")
 		}
@@ -221,25 +218,59 @@ func (rt *QuickJSRuntime) registerBuiltins() {
 		return ctx.ParseJSON(string(resultsJSON))
 	}))
 
-	// position()
-	globals.Set("position", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.currentState == nil {
+	// send(channel, message)
+	globals.Set("send", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if len(args) < 2 {
+			api.Warn("missing argument", "send", warningArgs(args))
 			return ctx.NewNull()
 		}
-		player := rt.currentState.PlayerRef(rt.playerID)
+		api.Send(args[0].String(), args[1].JSONStringify(), warningArgs(args))
+		return ctx.NewNull()
+	}))
+
+	// receive(channel)
+	globals.Set("receive", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if len(args) < 1 {
+			api.Warn("missing argument", "receive", warningArgs(args))
+			return ctx.ParseJSON("[]")
+		}
+
+		resultsJSON, _ := json.Marshal(api.Receive(args[0].String()))
+		return ctx.ParseJSON(string(resultsJSON))
+	}))
+
+	// storage.get()
+	globals.Set("storage_get", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if api.Storage() == nil {
+			return ctx.NewNull()
+		}
+		return ctx.ParseJSON(string(api.Storage()))
+	}))
+
+	// storage.set(value)
+	globals.Set("storage_set", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		if len(args) == 0 {
+			api.Warn("missing argument", "storage.set", warningArgs(args))
+			return ctx.NewNull()
+		}
+		api.StorageSet(args[0].JSONStringify(), warningArgs(args))
+		return ctx.NewNull()
+	}))
+
+	// position()
+	globals.Set("position", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		player := api.Player()
 		if player == nil {
 			return ctx.NewNull()
 		}
-		posJSON := fmt.Sprintf(`{"x": %f, "y": %f}`, player.X, player.Y)
-		return ctx.ParseJSON(posJSON)
+		// Marshal rather than format with a fixed width so every kept decimal place is reported
+		posJSON, _ := json.Marshal(map[string]float64{"x": player.X, "y": player.Y})
+		return ctx.ParseJSON(string(posJSON))
 	}))
 
 	// direction()
 	globals.Set("direction", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.currentState == nil {
-			return ctx.NewInt32(0)
-		}
-		player := rt.currentState.PlayerRef(rt.playerID)
+		player := api.Player()
 		if player == nil {
 			return ctx.NewInt32(0)
 		}
@@ -248,10 +279,7 @@ func (rt *QuickJSRuntime) registerBuiltins() {
 
 	// hp()
 	globals.Set("hp", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.currentState == nil {
-			return ctx.NewInt32(0)
-		}
-		player := rt.currentState.PlayerRef(rt.playerID)
+		player := api.Player()
 		if player == nil {
 			return ctx.NewInt32(0)
 		}
@@ -265,16 +293,33 @@ func (rt *QuickJSRuntime) registerBuiltins() {
 
 	// snowball_count()
 	globals.Set("snowball_count", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
-		if rt.currentState == nil {
-			return ctx.NewInt32(0)
-		}
-		player := rt.currentState.PlayerRef(rt.playerID)
+		player := api.Player()
 		if player == nil {
 			return ctx.NewInt32(0)
 		}
 		return ctx.NewInt32(int32(player.SnowballCount))
 	}))
 
+	// energy()
+	globals.Set("energy", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		return ctx.NewFloat64(api.Energy())
+	}))
+
+	// max_energy()
+	globals.Set("max_energy", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		return ctx.NewFloat64(api.MaxEnergy())
+	}))
+
+	// zone()
+	globals.Set("zone", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
+		zone := api.Zone()
+		if zone == nil {
+			return ctx.NewNull()
+		}
+		zoneJSON, _ := json.Marshal(zone)
+		return ctx.ParseJSON(string(zoneJSON))
+	}))
+
 	// max_snowball()
 	globals.Set("max_snowball", rt.ctx.NewFunction(func(ctx *quickjs.Context, this *quickjs.Value, args []*quickjs.Value) *quickjs.Value {
 		return ctx.NewInt32(int32(rt.Config.Snowbot.MaxSnowball))
@@ -283,6 +328,7 @@ func (rt *QuickJSRuntime) registerBuiltins() {
 	// Setup console object and deep freeze helper in JavaScript
 	initJS := `
 		globalThis.console = { log: console_log };
+		globalThis.storage = Object.freeze({ get: storage_get, set: storage_set });
 
 		globalThis.__deepFreeze = function(obj) {
 			const propNames = Object.getOwnPropertyNames(obj);
@@ -316,43 +362,34 @@ func (rt *QuickJSRuntime) Load(code string) error {
 
 // Run executes the 'run' function in the JS environment.
 func (rt *QuickJSRuntime) Run(state game.GameState) ([]game.Action, []Warning, error) {
-	// Reset actions for this tick
-	rt.currentActions = nil
-	rt.moveUsed = false
-	rt.turnUsed = false
-	rt.tossUsed = false
-	rt.warnings = nil
-	// Store current state for API functions to access
-	rt.currentState = &state
-
-	// Ensure Players slice is populated for scripts even if legacy fields were set.
-	if len(rt.currentState.Players) == 0 {
-		legacy := []game.Player{}
-		if rt.currentState.P1 != (game.Player{}) {
-			legacy = append(legacy, rt.currentState.P1)
-		}
-		if rt.currentState.P2 != (game.Player{}) {
-			legacy = append(legacy, rt.currentState.P2)
-		}
-		rt.currentState.Players = legacy
-	}
+	// Reset actions for this tick and store the state for API functions to access
+	rt.api.BeginTick(&state)
 
 	scriptState := rt.buildScriptState(state)
 	stateBytes, err := json.Marshal(scriptState)
 	if err != nil {
-		return nil, rt.warnings, fmt.Errorf("failed to marshal state: %w", err)
+		return nil, rt.api.Warnings(), fmt.Errorf("failed to marshal state: %w", err)
 	}
 
 	jsonStr := string(stateBytes)
 
-	// Configure per-tick interrupt handler for millisecond timeout
-	timedOut := false
-	if rt.Config.Runtime.TickTimeoutMs > 0 {
+	// Configure per-tick interrupt handler. The instruction budget is counted in
+	// interrupt handler invocations, which makes it reproducible across machines;
+	// the millisecond timeout is only a safety backstop.
+	budget := rt.Config.Runtime.MaxInstructionsPerTick
+	interrupts := 0
+	limitHit := ""
+	if budget > 0 || rt.Config.Runtime.TickTimeoutMs > 0 {
 		limit := time.Duration(rt.Config.Runtime.TickTimeoutMs) * time.Millisecond
 		start := time.Now()
 		rt.rt.SetInterruptHandler(func() int {
-			if time.Since(start) > limit {
-				timedOut = true
+			interrupts++
+			if budget > 0 && interrupts*instructionsPerInterrupt > budget {
+				limitHit = LimitInstructions
+				return 1
+			}
+			if limit > 0 && time.Since(start) > limit {
+				limitHit = LimitWallClock
 				return 1
 			}
 			return 0
@@ -367,10 +404,10 @@ func (rt *QuickJSRuntime) Run(state game.GameState) ([]game.Action, []Warning, e
 	// Set state json to a global variable
 	stateVal := rt.ctx.ParseJSON(jsonStr)
 	if stateVal == nil {
-		return nil, rt.warnings, fmt.Errorf("failed to parse state json")
+		return nil, rt.api.Warnings(), fmt.Errorf("failed to parse state json")
 	}
 	if stateVal.IsException() {
-		return nil, rt.warnings, fmt.Errorf("failed to parse state json: %w", rt.ctx.Exception())
+		return nil, rt.api.Warnings(), fmt.Errorf("failed to parse state json: %w", rt.ctx.Exception())
 	}
 	globals.Set("__state_json", stateVal)
 
@@ -392,7 +429,7 @@ func (rt *QuickJSRuntime) Run(state game.GameState) ([]game.Action, []Warning, e
 
 	runFn := globals.Get("run")
 	if runFn == nil || !runFn.IsFunction() {
-		return nil, rt.warnings, fmt.Errorf("run is not defined or not a function")
+		return nil, rt.api.Warnings(), fmt.Errorf("run is not defined or not a function")
 	}
 	defer runFn.Free()
 
@@ -405,27 +442,32 @@ func (rt *QuickJSRuntime) Run(state game.GameState) ([]game.Action, []Warning, e
 		defer undef.Free()
 	}
 
-	if timedOut {
-		rt.addWarning("execution timed out", "run", nil)
-		return rt.currentActions, rt.warnings, nil
+	if limitHit != "" {
+		rt.api.WarnTimeout(limitHit, interrupts*instructionsPerInterrupt, budget)
+		return rt.api.Actions(), rt.api.Warnings(), nil
 	}
 
 	if result != nil && result.IsException() {
 		exErr := rt.ctx.Exception()
 		if exErr != nil {
-			rt.addWarning(exErr.Error(), "run", nil)
+			rt.api.Warn(exErr.Error(), "run", nil)
 		} else {
 			// フォールバック: OOMなどで例外文字列が取れないケース
-			rt.addWarning("execution error (possibly out of memory)", "run", nil)
+			rt.api.Warn("execution error (possibly out of memory)", "run", nil)
 		}
-		return rt.currentActions, rt.warnings, nil
+		return rt.api.Actions(), rt.api.Warnings(), nil
 	}
 
-	return rt.currentActions, rt.warnings, nil
+	return rt.api.Actions(), rt.api.Warnings(), nil
 }
 
 func (rt *QuickJSRuntime) buildScriptState(state game.GameState) ScriptState {
-	player := state.PlayerRef(rt.playerID)
+	return NewScriptState(state, rt.api.PlayerID)
+}
+
+// NewScriptState builds the state passed to run() for the given 1-based player.
+func NewScriptState(state game.GameState, playerID int) ScriptState {
+	player := state.PlayerRef(playerID)
 	if player == nil {
 		return ScriptState{
 			Tick: state.Tick,
@@ -438,28 +480,17 @@ func (rt *QuickJSRuntime) buildScriptState(state game.GameState) ScriptState {
 		Angle:         player.Angle,
 		HP:            player.HP,
 		SnowballCount: player.SnowballCount,
+		GatherTicks:   player.GatherTicks,
+		Team:          player.Team,
+		Speed:         player.Speed,
 	}
 }
 
-func (rt *QuickJSRuntime) addWarning(msg, api string, args []*quickjs.Value) {
-	if len(rt.warnings) >= 3 {
-		// hard cap per tick
-		return
-	}
+// warningArgs converts script arguments to the strings reported in warnings.
+func warningArgs(args []*quickjs.Value) []interface{} {
 	converted := make([]interface{}, 0, len(args))
 	for _, a := range args {
 		converted = append(converted, a.String())
 	}
-	// tick is taken from currentState (may be nil on early calls)
-	tick := 0
-	if rt.currentState != nil {
-		tick = rt.currentState.Tick
-	}
-	rt.warnings = append(rt.warnings, Warning{
-		Warning: msg,
-		Tick:    tick,
-		Player:  rt.playerID,
-		API:     api,
-		Args:    converted,
-	})
+	return converted
 }
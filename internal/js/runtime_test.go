@@ -245,6 +245,41 @@ func TestToss_NegativeDistance(t *testing.T) {
 	}
 }
 
+func TestMakeSnowball_API(t *testing.T) {
+	cfg := config.Default()
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	code := `function run(state) { make_snowball(); make_snowball(); }`
+	if err := rt.Load(code); err != nil {
+		t.Fatal(err)
+	}
+
+	actions, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Type != game.ActionGather {
+		t.Fatalf("expected single ActionGather, got %+v", actions)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning for duplicate call, got %d", len(warnings))
+	}
+
+	// Already gathering: no action and a warning
+	state := game.GameState{Players: []game.Player{{GatherTicks: 2}}}
+	actions, warnings, err = rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no action while gathering, got %d", len(actions))
+	}
+	if len(warnings) != 2 || warnings[0].Warning != "already making snowballs" {
+		t.Errorf("expected already-gathering warning, got %+v", warnings)
+	}
+}
+
 func TestActionAccumulation(t *testing.T) {
 	cfg := config.Default()
 	rt := NewQuickJSRuntime(cfg, 1)
@@ -408,8 +443,8 @@ func TestScan_API(t *testing.T) {
 	// P1 at (-50, 0) facing East (90)
 	// P2 at (50, 0) -> Distance 100, Angle 90 from P1
 	state := game.GameState{
-		P1: game.Player{X: -50, Y: 0, Angle: 90},
-		P2: game.Player{X: 50, Y: 0},
+		P1: game.Player{X: -50, Y: 0, HP: 100, Angle: 90},
+		P2: game.Player{X: 50, Y: 0, HP: 100},
 	}
 
 	// Test case 1: Enemy in front (90 degrees), scan 90 +/- 22.5
@@ -430,8 +465,8 @@ func TestScan_API(t *testing.T) {
 	if err := rt.Load(code1); err != nil {
 		t.Fatalf("failed to load code1: %v", err)
 	}
-    if _, _, err := rt.Run(state); err != nil {
-        t.Errorf("TestScan_API case 1 failed: %v", err)
+    if _, warnings, err := rt.Run(state); err != nil || len(warnings) > 0 {
+        t.Errorf("TestScan_API case 1 failed: %v %+v", err, warnings)
     }
 
 	// Test case 2: Enemy out of angle (scan north 0 +/- 22.5)
@@ -446,11 +481,73 @@ func TestScan_API(t *testing.T) {
 	if err := rt.Load(code2); err != nil {
 		t.Fatalf("failed to load code2: %v", err)
 	}
-    if _, _, err := rt.Run(state); err != nil {
-        t.Errorf("TestScan_API case 2 failed: %v", err)
+    if _, warnings, err := rt.Run(state); err != nil || len(warnings) > 0 {
+        t.Errorf("TestScan_API case 2 failed: %v %+v", err, warnings)
     }
 }
 
+func TestScanSnowballs_API(t *testing.T) {
+	cfg := config.Default()
+	rt := NewQuickJSRuntime(cfg, 1) // Player 1
+	defer rt.Close()
+
+	// P1 at (0, 0); P2's snowball at (0, 50) flying south towards P1, P1's own snowball at (0, 30)
+	state := game.GameState{
+		Players: []game.Player{{X: 0, Y: 0, HP: 100}, {X: 100, Y: 100, HP: 100}},
+		Snowballs: []game.Snowball{
+			{ID: 1, OwnerID: 2, X: 0, Y: 50, VX: 0, VY: -10},
+			{ID: 2, OwnerID: 1, X: 0, Y: 30, VX: 0, VY: 10},
+		},
+	}
+
+	code := `
+		function run(state) {
+			var results = scan_snowballs(0, 20);
+			if (results.length !== 1) {
+				throw new Error("expected 1 result, got " + results.length);
+			}
+			if (results[0].type !== "snowball") {
+				throw new Error("expected snowball");
+			}
+			if (Math.abs(results[0].distance - 50) > 0.1) {
+				throw new Error("expected distance 50, got " + results[0].distance);
+			}
+			if (Math.abs(results[0].heading - 180) > 0.1) {
+				throw new Error("expected heading 180, got " + results[0].heading);
+			}
+			if (Math.abs(results[0].speed - 10) > 0.1) {
+				throw new Error("expected speed 10, got " + results[0].speed);
+			}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatalf("failed to load code: %v", err)
+	}
+	if _, warnings, err := rt.Run(state); err != nil || len(warnings) > 0 {
+		t.Errorf("scan_snowballs failed: %v %+v", err, warnings)
+	}
+
+	// Disabled by config: empty result and a warning
+	cfg.Sensor.ScanSnowballs = false
+	code = `
+		function run(state) {
+			if (scan_snowballs(0, 20).length !== 0) {
+				throw new Error("expected no results when disabled");
+			}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatalf("failed to load code: %v", err)
+	}
+	_, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Errorf("scan_snowballs (disabled) failed: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "disabled by config" {
+		t.Errorf("expected one 'disabled by config' warning, got %+v", warnings)
+	}
+}
+
 func TestRun_TimeoutWarning(t *testing.T) {
 	cfg := config.Default()
 	cfg.Runtime.TickTimeoutMs = 50
@@ -492,6 +589,75 @@ func TestRun_TimeoutWarning(t *testing.T) {
 	}
 }
 
+func TestRun_InstructionBudgetWarning(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.MaxInstructionsPerTick = 50000
+	cfg.Runtime.TickTimeoutMs = 0
+
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	code := `
+		function run(state) {
+			move(5);
+			while (true) {}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatalf("failed to load code: %v", err)
+	}
+
+	actions, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatalf("expected budget exhaustion to surface as warning, got error: %v", err)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("expected actions issued before exhaustion to be kept, got %d", len(actions))
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %+v", warnings)
+	}
+	w := warnings[0]
+	if w.Warning != "execution timed out" || w.Limit != LimitInstructions {
+		t.Fatalf("expected instruction budget timeout, got %+v", w)
+	}
+	if w.InstructionBudget != 50000 || w.InstructionsUsed <= w.InstructionBudget {
+		t.Fatalf("expected usage over budget 50000, got used=%d budget=%d", w.InstructionsUsed, w.InstructionBudget)
+	}
+}
+
+func TestRun_InstructionBudgetDeterministic(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.MaxInstructionsPerTick = 100000
+	cfg.Runtime.TickTimeoutMs = 0
+
+	// A loop whose length depends only on the budget must stop at the same point every time.
+	code := `
+		var count = 0;
+		function run(state) {
+			while (true) { count++; }
+		}
+		function result() { return count; }
+	`
+	counts := make([]int32, 2)
+	for i := range counts {
+		rt := NewQuickJSRuntime(cfg, 1)
+		if err := rt.Load(code); err != nil {
+			t.Fatalf("failed to load code: %v", err)
+		}
+		if _, _, err := rt.Run(game.GameState{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		val := rt.ctx.Eval("result()")
+		counts[i] = val.ToInt32()
+		val.Free()
+		rt.Close()
+	}
+	if counts[0] == 0 || counts[0] != counts[1] {
+		t.Fatalf("expected identical non-zero iteration counts, got %v", counts)
+	}
+}
+
 func TestRun_OutOfMemoryWarning(t *testing.T) {
 	cfg := config.Default()
 	cfg.Runtime.MaxMemoryBytes = 262144 // 256KB to provoke OOM
@@ -525,3 +691,212 @@ func TestRun_OutOfMemoryWarning(t *testing.T) {
 		t.Fatalf("expected execution error warning, got %+v", warnings)
 	}
 }
+
+func TestRadio_SendReceive(t *testing.T) {
+	cfg := config.Default()
+	cfg.Radio.MaxMessageBytes = 32
+	cfg.Radio.MaxBytesPerTick = 40
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	code := `
+		function run(state) {
+			var got = receive("team");
+			if (got.length !== 1 || got[0].from !== 2 || got[0].message.x !== 5) {
+				throw new Error("unexpected receive: " + JSON.stringify(got));
+			}
+			send("team", {x: 1});                 // 11 bytes
+			send("team", "a".repeat(40));         // too large
+			send("team", {y: "0123456789"});      // 22 bytes, 33 in total
+			send("team", {z: 1});                 // exceeds the per-tick bandwidth
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatal(err)
+	}
+	rt.Deliver([]RadioMessage{
+		{From: 2, Channel: "team", Message: []byte(`{"x":5}`)},
+		{From: 3, Channel: "other", Message: []byte(`1`)},
+	})
+	_, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := rt.Sent()
+	if len(sent) != 2 || string(sent[0].Message) != `{"x":1}` || sent[1].From != 1 {
+		t.Errorf("expected two sent messages, got %+v", sent)
+	}
+	if len(warnings) != 2 || warnings[0].Warning != "message too large" || warnings[1].Warning != "bandwidth exceeded" {
+		t.Errorf("expected size and bandwidth warnings, got %+v", warnings)
+	}
+
+	// The outbox is reset every tick
+	rt.Deliver(nil)
+	if err := rt.Load(`function run(state) { if (receive("team").length !== 0) throw new Error("stale inbox"); }`); err != nil {
+		t.Fatal(err)
+	}
+	_, warnings, err = rt.Run(game.GameState{})
+	if err != nil || len(warnings) != 0 || len(rt.Sent()) != 0 {
+		t.Errorf("expected empty tick, got err=%v warnings=%+v sent=%+v", err, warnings, rt.Sent())
+	}
+}
+
+func TestStorage_GetSet(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.MaxBytes = 32
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+	rt.SetStorage([]byte(`{"wins":2}`))
+
+	// The stored value is readable from the top-level code
+	code := `
+		var memory = storage.get();
+		function run(state) {
+			storage.set("x".repeat(40));          // too large, keeps the old value
+			storage.set({wins: memory.wins + 1});
+			if (storage.get().wins !== 3) {
+				throw new Error("unexpected storage: " + JSON.stringify(storage.get()));
+			}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatal(err)
+	}
+	_, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "value too large" {
+		t.Errorf("expected a single size warning, got %+v", warnings)
+	}
+	if got := string(rt.Storage()); got != `{"wins":3}` {
+		t.Errorf("expected stored value {\"wins\":3}, got %s", got)
+	}
+
+	// Without SetStorage the API is inert
+	plain := NewQuickJSRuntime(cfg, 1)
+	defer plain.Close()
+	if err := plain.Load(`function run(state) { if (storage.get() !== null) throw new Error("unexpected value"); storage.set(1); }`); err != nil {
+		t.Fatal(err)
+	}
+	_, warnings, err = plain.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "storage not enabled for this match" || plain.Storage() != nil {
+		t.Errorf("expected storage to be disabled, got warnings=%+v storage=%s", warnings, plain.Storage())
+	}
+}
+
+func TestEnergy_API(t *testing.T) {
+	cfg := config.Default()
+	cfg.Energy.Enabled = true
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	code := `
+		function run(state) {
+			if (max_energy() !== 100 || energy() !== 25) {
+				throw new Error("unexpected energy: " + energy() + "/" + max_energy());
+			}
+			scan(0, 30);    // 2
+			toss(50);       // 20
+			move(10);       // 10, not enough energy left
+			if (energy() !== 3) {
+				throw new Error("unexpected energy left: " + energy());
+			}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatal(err)
+	}
+	state := game.GameState{Players: []game.Player{{HP: 100, Energy: 25}}}
+	actions, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "not enough energy" || warnings[0].API != "move" {
+		t.Errorf("expected a single energy warning for move, got %+v", warnings)
+	}
+	if len(actions) != 2 || actions[0].Type != game.ActionScan || actions[1].Type != game.ActionToss {
+		t.Errorf("expected scan and toss actions, got %+v", actions)
+	}
+}
+
+func TestTurn_MaxTurnPerTick(t *testing.T) {
+	cfg := config.Default()
+	cfg.Snowbot.MaxTurnPerTick = 45
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(`function run(state) { turn(-120); }`); err != nil {
+		t.Fatal(err)
+	}
+	actions, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Value != -45 {
+		t.Errorf("expected turn clamped to -45, got %+v", actions)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "clamped to max_turn_per_tick" {
+		t.Errorf("expected a clamp warning, got %+v", warnings)
+	}
+}
+
+func TestNewRuntime_Registry(t *testing.T) {
+	cfg := config.Default()
+
+	rt, err := NewRuntime("bots/Bot.JS", cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	if _, ok := rt.(*QuickJSRuntime); !ok {
+		t.Errorf("expected a QuickJS runtime for .js, got %T", rt)
+	}
+
+	// Specs without a registered extension keep running on QuickJS
+	for _, spec := range []string{"bots/bot.py", "https://gist.githubusercontent.com/u/abc/raw", "https://example.com/bot.js?token=x"} {
+		rt, err := NewRuntime(spec, cfg, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", spec, err)
+		}
+		if _, ok := rt.(*QuickJSRuntime); !ok {
+			t.Errorf("%s: expected a QuickJS runtime, got %T", spec, rt)
+		}
+		rt.Close()
+	}
+}
+
+func TestZone_API(t *testing.T) {
+	cfg := config.Default()
+	rt := NewQuickJSRuntime(cfg, 1)
+	defer rt.Close()
+
+	code := `
+		function run(state) {
+			const z = zone();
+			if (state.tick === 1 && z !== null) {
+				throw new Error("expected no zone, got " + JSON.stringify(z));
+			}
+			if (state.tick === 310 && (z.x !== 0 || z.y !== 0 || Math.abs(z.radius - (Math.hypot(500, 500) - 10)) > 1e-9 || z.final_radius !== 100)) {
+				throw new Error("unexpected zone: " + JSON.stringify(z));
+			}
+		}
+	`
+	if err := rt.Load(code); err != nil {
+		t.Fatal(err)
+	}
+	for _, tick := range []int{1, 310} {
+		cfg.Zone.Enabled = tick > 1
+		_, warnings, err := rt.Run(game.GameState{Tick: tick, Players: []game.Player{{HP: 100}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("tick %d: unexpected warnings %+v", tick, warnings)
+		}
+	}
+}
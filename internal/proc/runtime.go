@@ -0,0 +1,418 @@
+package proc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/js"
+	"strings"
+	"time"
+)
+
+// Scheme is the bot spec prefix of subprocess bots, as in "proc:./mybot --level 3".
+const Scheme = "proc"
+
+// startupTimeout is how long a bot process may take to answer the init message.
+const startupTimeout = 5 * time.Second
+
+// fallbackTickTimeout bounds a tick when runtime.tick_timeout_ms is 0, so a stuck process
+// cannot hang the match.
+const fallbackTickTimeout = 5 * time.Second
+
+// maxLineBytes caps the length of a line a bot process writes.
+const maxLineBytes = 1 << 20
+
+func init() {
+	js.RegisterScheme(Scheme, func(cfg *config.Config, playerID int) js.Runtime {
+		return NewRuntime(cfg, playerID)
+	})
+}
+
+// Message is a line of the stdio protocol. The host sends "init", "tick" and "result"
+// messages; the bot answers with "ready", "query" and "actions" messages.
+type Message struct {
+	Type string `json:"type"`
+
+	// init
+	Player  int             `json:"player,omitempty"`
+	Params  *Params         `json:"params,omitempty"`
+	Storage json.RawMessage `json:"storage,omitempty"`
+
+	// tick
+	State *js.ScriptState `json:"state,omitempty"`
+
+	// query (API and Args) and result (Value)
+	API   string            `json:"api,omitempty"`
+	Args  []json.RawMessage `json:"args,omitempty"`
+	Value interface{}       `json:"value,omitempty"`
+
+	// actions
+	Actions []Call `json:"actions,omitempty"`
+}
+
+// Call is an API call in an "actions" message.
+type Call struct {
+	API  string            `json:"api"`
+	Args []json.RawMessage `json:"args"`
+}
+
+// Params are the fixed values JavaScript bots read with max_hp(), max_snowball() and max_energy().
+type Params struct {
+	MaxHP       int     `json:"max_hp"`
+	MaxSnowball int     `json:"max_snowball"`
+	MaxEnergy   float64 `json:"max_energy"`
+}
+
+// line is a line read from the bot process, or the error that ended its output.
+type line struct {
+	data []byte
+	err  error
+}
+
+// Runtime implements js.Runtime by running the bot as a subprocess that exchanges one JSON
+// message per line over stdin/stdout. Each tick must be answered within runtime.tick_timeout_ms
+// (or fallbackTickTimeout when it is 0); a process that misses the deadline, including by not
+// reading its input, or breaks the protocol is killed and stays idle.
+type Runtime struct {
+	Config *config.Config
+	bot    *js.API
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan line
+	killed bool
+}
+
+// NewRuntime creates a new subprocess Runtime instance.
+func NewRuntime(cfg *config.Config, playerID int) *Runtime {
+	return &Runtime{
+		Config: cfg,
+		bot:    js.NewAPI(cfg, playerID),
+	}
+}
+
+func (rt *Runtime) Close() {
+	rt.kill()
+}
+
+// Deliver sets the radio messages receive returns during the next Run.
+func (rt *Runtime) Deliver(messages []js.RadioMessage) {
+	rt.bot.Deliver(messages)
+}
+
+// Sent returns the radio messages sent during the last Run.
+func (rt *Runtime) Sent() []js.RadioMessage {
+	return rt.bot.Sent()
+}
+
+// SetStorage enables storage.get/set with the given stored value (nil for none).
+// Call it before Load so the value is part of the init message.
+func (rt *Runtime) SetStorage(data json.RawMessage) {
+	rt.bot.SetStorage(data)
+}
+
+// Storage returns the value stored with storage.set, or nil if there is none.
+func (rt *Runtime) Storage() json.RawMessage {
+	return rt.bot.Storage()
+}
+
+// Load starts the command line (split on spaces, without a shell) and waits for the bot to
+// answer the init message with "ready".
+func (rt *Runtime) Load(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return errors.New("empty command")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	rt.cmd = cmd
+	rt.stdin = stdin
+	rt.lines = make(chan line)
+	go readLines(stdout, rt.lines)
+
+	initMsg := Message{
+		Type:   "init",
+		Player: rt.bot.PlayerID,
+		Params: &Params{
+			MaxHP:       rt.Config.Snowbot.MaxHP,
+			MaxSnowball: rt.Config.Snowbot.MaxSnowball,
+			MaxEnergy:   rt.bot.MaxEnergy(),
+		},
+		Storage: rt.bot.Storage(),
+	}
+	deadline := time.Now().Add(startupTimeout)
+	if err := rt.send(initMsg, deadline); err != nil {
+		rt.kill()
+		return err
+	}
+	msg, err := rt.receive(deadline)
+	if err == nil && msg.Type != "ready" {
+		err = fmt.Errorf("expected a ready message, got %q", msg.Type)
+	}
+	if err != nil {
+		rt.kill()
+		return err
+	}
+	return nil
+}
+
+// Run sends the tick state to the bot, answers its queries and applies the actions it returns.
+func (rt *Runtime) Run(state game.GameState) ([]game.Action, []js.Warning, error) {
+	// Reset actions for this tick and store the state for API functions to access
+	rt.bot.BeginTick(&state)
+	if rt.killed {
+		return nil, nil, nil
+	}
+
+	timeout := fallbackTickTimeout
+	if rt.Config.Runtime.TickTimeoutMs > 0 {
+		timeout = time.Duration(rt.Config.Runtime.TickTimeoutMs) * time.Millisecond
+	}
+	deadline := time.Now().Add(timeout)
+
+	scriptState := js.NewScriptState(state, rt.bot.PlayerID)
+	if err := rt.send(Message{Type: "tick", State: &scriptState}, deadline); err != nil {
+		rt.fail(err)
+		return rt.bot.Actions(), rt.bot.Warnings(), nil
+	}
+
+	for {
+		msg, err := rt.receive(deadline)
+		if err != nil {
+			rt.fail(err)
+			return rt.bot.Actions(), rt.bot.Warnings(), nil
+		}
+
+		switch msg.Type {
+		case "query":
+			if err := rt.send(Message{Type: "result", Value: rt.query(msg.API, msg.Args)}, deadline); err != nil {
+				rt.fail(err)
+				return rt.bot.Actions(), rt.bot.Warnings(), nil
+			}
+		case "actions":
+			for _, call := range msg.Actions {
+				rt.call(call.API, call.Args)
+			}
+			return rt.bot.Actions(), rt.bot.Warnings(), nil
+		default:
+			rt.fail(fmt.Errorf("unexpected message type %q", msg.Type))
+			return rt.bot.Actions(), rt.bot.Warnings(), nil
+		}
+	}
+}
+
+// query answers a query message with the value the JavaScript API returns.
+func (rt *Runtime) query(api string, args []json.RawMessage) interface{} {
+	bot := rt.bot
+	wargs := warningArgs(args)
+	switch api {
+	case "scan", "scan_snowballs":
+		if len(args) < 2 {
+			bot.Warn("missing argument", api, wargs)
+			return []interface{}{}
+		}
+		angle, resolution := intArg(args[0]), intArg(args[1])
+		if api == "scan" {
+			if results := bot.Scan(angle, resolution, wargs); len(results) > 0 {
+				return results
+			}
+		} else if results := bot.ScanSnowballs(angle, resolution, wargs); len(results) > 0 {
+			return results
+		}
+		return []interface{}{}
+	case "receive":
+		if len(args) < 1 {
+			bot.Warn("missing argument", api, wargs)
+			return []interface{}{}
+		}
+		return bot.Receive(stringArg(args[0]))
+	case "storage.get":
+		if bot.Storage() == nil {
+			return nil
+		}
+		return bot.Storage()
+	case "energy":
+		return bot.Energy()
+	case "zone":
+		if zone := bot.Zone(); zone != nil {
+			return zone
+		}
+		return nil
+	}
+	bot.Warn("unknown API", api, wargs)
+	return nil
+}
+
+// call applies an API call of an actions message.
+func (rt *Runtime) call(api string, args []json.RawMessage) {
+	bot := rt.bot
+	wargs := warningArgs(args)
+	switch api {
+	case "move", "turn", "toss":
+		if !bot.FirstCall(api, wargs) {
+			return
+		}
+		if len(args) < 1 {
+			bot.Warn("missing argument", api, wargs)
+			return
+		}
+		switch api {
+		case "move":
+			bot.Move(intArg(args[0]), wargs)
+		case "turn":
+			bot.Turn(intArg(args[0]), wargs)
+		case "toss":
+			bot.Toss(intArg(args[0]), wargs)
+		}
+	case "make_snowball":
+		if bot.FirstCall(api, wargs) {
+			bot.MakeSnowball(wargs)
+		}
+	case "send":
+		if len(args) < 2 {
+			bot.Warn("missing argument", api, wargs)
+			return
+		}
+		bot.Send(stringArg(args[0]), string(args[1]), wargs)
+	case "storage.set":
+		if len(args) < 1 {
+			bot.Warn("missing argument", api, wargs)
+			return
+		}
+		bot.StorageSet(string(args[0]), wargs)
+	default:
+		bot.Warn("unknown API", api, wargs)
+	}
+}
+
+// send writes a message line, giving up at deadline when the process does not read its input.
+// The blocked write ends when the process is killed.
+func (rt *Runtime) send(msg Message, deadline time.Time) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := rt.stdin.Write(append(data, '\n'))
+		done <- err
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errTimeout
+	}
+}
+
+// receive waits for the next message until deadline.
+func (rt *Runtime) receive(deadline time.Time) (Message, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	var msg Message
+	select {
+	case l, ok := <-rt.lines:
+		if !ok {
+			return msg, errors.New("process exited")
+		}
+		if l.err != nil {
+			return msg, l.err
+		}
+		if err := json.Unmarshal(l.data, &msg); err != nil {
+			return msg, fmt.Errorf("invalid message: %w", err)
+		}
+		return msg, nil
+	case <-timer.C:
+		return msg, errTimeout
+	}
+}
+
+var errTimeout = errors.New("execution timed out")
+
+// fail kills the process after a missed deadline or a protocol error and reports it as a warning.
+func (rt *Runtime) fail(err error) {
+	if errors.Is(err, errTimeout) {
+		rt.bot.WarnTimeout(js.LimitWallClock, 0, 0)
+	} else {
+		rt.bot.Warn(err.Error(), "run", nil)
+	}
+	rt.kill()
+}
+
+func (rt *Runtime) kill() {
+	if rt.killed || rt.cmd == nil {
+		return
+	}
+	rt.killed = true
+	rt.stdin.Close()
+	rt.cmd.Process.Kill()
+	// Drain the output so the reader goroutine can finish
+	go func() {
+		for range rt.lines {
+		}
+	}()
+	rt.cmd.Wait()
+}
+
+// readLines sends each line of r to lines and closes it when the output ends.
+func readLines(r io.Reader, lines chan<- line) {
+	defer close(lines)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		data := append([]byte(nil), scanner.Bytes()...)
+		lines <- line{data: data}
+	}
+	if err := scanner.Err(); err != nil {
+		lines <- line{err: fmt.Errorf("reading output: %w", err)}
+	}
+}
+
+// intArg converts a JSON argument to an integer the way the JavaScript API does.
+func intArg(arg json.RawMessage) int {
+	var f float64
+	json.Unmarshal(arg, &f)
+	return int(f)
+}
+
+// stringArg converts a JSON argument to a string the way the JavaScript API does.
+func stringArg(arg json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(arg, &s); err != nil {
+		return string(arg)
+	}
+	return s
+}
+
+// warningArgs converts call arguments to the strings reported in warnings.
+func warningArgs(args []json.RawMessage) []interface{} {
+	converted := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		converted = append(converted, stringArg(a))
+	}
+	return converted
+}
+
+var _ js.Runtime = (*Runtime)(nil)
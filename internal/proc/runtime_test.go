@@ -0,0 +1,170 @@
+package proc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/js"
+	"testing"
+	"time"
+)
+
+// TestHelperBot is not a real test: it is the bot process started by the other tests.
+// SNOWBOT_HELPER selects its behavior.
+func TestHelperBot(t *testing.T) {
+	mode := os.Getenv("SNOWBOT_HELPER")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	in := bufio.NewScanner(os.Stdin)
+	send := func(msg string) {
+		fmt.Println(msg)
+	}
+	for in.Scan() {
+		var msg Message
+		json.Unmarshal(in.Bytes(), &msg)
+		if mode == "garbage" {
+			send("not json")
+			continue
+		}
+		if msg.Type == "init" {
+			send(`{"type":"ready"}`)
+			continue
+		}
+
+		switch mode {
+		case "fighter":
+			send(`{"type":"query","api":"scan","args":[0,30]}`)
+			in.Scan()
+			send(`{"type":"actions","actions":[{"api":"move","args":[10]},{"api":"move","args":[5]},{"api":"send","args":["team",{"x":1}]}]}`)
+		case "slow":
+			time.Sleep(time.Second)
+			send(`{"type":"actions","actions":[]}`)
+		case "flood":
+			// Queries without ever reading the results, until the host's writes block
+			for {
+				send(`{"type":"query","api":"scan","args":[0,30]}`)
+			}
+		}
+	}
+}
+
+func helperCommand(t *testing.T, mode string) string {
+	t.Setenv("SNOWBOT_HELPER", mode)
+	return os.Args[0] + " -test.run=^TestHelperBot$"
+}
+
+func TestRun_QueriesAndActions(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.TickTimeoutMs = 5000
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(helperCommand(t, "fighter")); err != nil {
+		t.Fatal(err)
+	}
+
+	state := game.GameState{Tick: 1, Players: []game.Player{{HP: 100}, {HP: 100}}}
+	actions, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 1 || actions[0].Type != game.ActionMove || actions[0].Value != 10 {
+		t.Errorf("expected a single move 10, got %+v", actions)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "called multiple times in one tick" || warnings[0].API != "move" {
+		t.Errorf("expected a duplicate move warning, got %+v", warnings)
+	}
+	sent := rt.Sent()
+	if len(sent) != 1 || sent[0].Channel != "team" || string(sent[0].Message) != `{"x":1}` {
+		t.Errorf("expected a radio message on team, got %+v", sent)
+	}
+}
+
+func TestRun_DeadlineKillsProcess(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.TickTimeoutMs = 50
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(helperCommand(t, "slow")); err != nil {
+		t.Fatal(err)
+	}
+
+	state := game.GameState{Tick: 1, Players: []game.Player{{HP: 100}}}
+	_, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Warning != "execution timed out" || warnings[0].Limit != js.LimitWallClock {
+		t.Fatalf("expected a wall-clock timeout warning, got %+v", warnings)
+	}
+
+	// The killed bot stays idle without further warnings
+	start := time.Now()
+	actions, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 || len(warnings) != 0 {
+		t.Errorf("expected no actions or warnings after the kill, got %+v %+v", actions, warnings)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the killed bot to return immediately")
+	}
+}
+
+func TestRun_FloodingQueriesKillsProcess(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.TickTimeoutMs = 200
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(helperCommand(t, "flood")); err != nil {
+		t.Fatal(err)
+	}
+
+	state := game.GameState{Tick: 1, Players: []game.Player{{HP: 100}}}
+	done := make(chan []js.Warning, 1)
+	go func() {
+		_, warnings, _ := rt.Run(state)
+		done <- warnings
+	}()
+	select {
+	case warnings := <-done:
+		if len(warnings) != 1 || warnings[0].Limit != js.LimitWallClock {
+			t.Errorf("expected a wall-clock timeout warning, got %+v", warnings)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run blocked on a bot that does not read its input")
+	}
+}
+
+func TestLoad_ProtocolError(t *testing.T) {
+	cfg := config.Default()
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(helperCommand(t, "garbage")); err == nil {
+		t.Fatal("expected an error for a bot that does not answer ready")
+	}
+}
+
+func TestNewRuntime_Scheme(t *testing.T) {
+	if scheme, target := js.SplitSpec("proc:./mybot --level 3"); scheme != Scheme || target != "./mybot --level 3" {
+		t.Errorf("expected proc scheme, got %q %q", scheme, target)
+	}
+	rt, err := js.NewRuntime("proc:./mybot", config.Default(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rt.(*Runtime); !ok {
+		t.Errorf("expected a subprocess runtime, got %T", rt)
+	}
+}
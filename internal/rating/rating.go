@@ -0,0 +1,253 @@
+package rating
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// System identifies a rating algorithm.
+type System string
+
+const (
+	Elo     System = "elo"
+	Glicko2 System = "glicko2"
+)
+
+// Default parameters for new ratings and the update algorithms.
+const (
+	InitialRating     = 1500.0
+	InitialDeviation  = 350.0 // Glicko-2 only
+	InitialVolatility = 0.06  // Glicko-2 only
+	EloK              = 32.0
+	glicko2Tau        = 0.5
+	glicko2Scale      = 173.7178
+	glicko2Epsilon    = 0.000001
+)
+
+// ParseSystem converts a command-line name into a System.
+func ParseSystem(name string) (System, error) {
+	switch System(name) {
+	case Elo, Glicko2:
+		return System(name), nil
+	}
+	return "", fmt.Errorf("unknown rating system %q (want elo or glicko2)", name)
+}
+
+// Rating is a single bot's rating.
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation,omitempty"`  // Glicko-2 rating deviation
+	Volatility float64 `json:"volatility,omitempty"` // Glicko-2 volatility
+	Games      int     `json:"games"`
+}
+
+// Game is the outcome of one game between bots A and B for rating purposes.
+type Game struct {
+	A      string
+	B      string
+	ScoreA float64 // 1 = A won, 0.5 = draw, 0 = B won
+	// Match groups the pairwise games of one free-for-all match, which count once in
+	// Rating.Games. Zero means the game is a match of its own.
+	Match int
+}
+
+// Table holds the ratings of all bots, persisted as JSON between league runs.
+type Table struct {
+	System  System            `json:"system"`
+	Updated string            `json:"updated,omitempty"`
+	Ratings map[string]Rating `json:"ratings"`
+}
+
+// NewTable creates an empty table for the given system.
+func NewTable(system System) *Table {
+	return &Table{System: system, Ratings: map[string]Rating{}}
+}
+
+// Load reads a rating table from path. A missing file yields an empty table.
+func Load(path string, system System) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewTable(system), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ratings: %w", err)
+	}
+
+	table := NewTable(system)
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, fmt.Errorf("parsing ratings %s: %w", path, err)
+	}
+	if table.System != system {
+		return nil, fmt.Errorf("ratings file %s uses %s, not %s", path, table.System, system)
+	}
+	if table.Ratings == nil {
+		table.Ratings = map[string]Rating{}
+	}
+	return table, nil
+}
+
+// Save writes the table to path as indented JSON.
+func (t *Table) Save(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding ratings: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing ratings: %w", err)
+	}
+	return nil
+}
+
+// Get returns the rating of a bot, or the initial rating if it has none yet.
+func (t *Table) Get(name string) Rating {
+	if r, ok := t.Ratings[name]; ok {
+		return r
+	}
+	r := Rating{Rating: InitialRating}
+	if t.System == Glicko2 {
+		r.Deviation = InitialDeviation
+		r.Volatility = InitialVolatility
+	}
+	return r
+}
+
+// Update applies games as one rating period. All updates are computed from the
+// ratings before the period, so the result does not depend on the order of games.
+// For Elo, K is spread over the games of the period, so one period moves a rating
+// by at most EloK however many games it has.
+func (t *Table) Update(games []Game) {
+	opponents := map[string][]opponent{}
+	for _, g := range games {
+		if g.A == g.B {
+			continue
+		}
+		opponents[g.A] = append(opponents[g.A], opponent{name: g.B, score: g.ScoreA, match: g.Match})
+		opponents[g.B] = append(opponents[g.B], opponent{name: g.A, score: 1 - g.ScoreA, match: g.Match})
+	}
+
+	updated := map[string]Rating{}
+	for name, opps := range opponents {
+		switch t.System {
+		case Glicko2:
+			updated[name] = t.glicko2(t.Get(name), opps)
+		default:
+			updated[name] = t.elo(t.Get(name), opps)
+		}
+	}
+
+	// Bots that did not play this period still accumulate uncertainty.
+	if t.System == Glicko2 {
+		for name, r := range t.Ratings {
+			if _, played := updated[name]; !played {
+				phi := r.Deviation / glicko2Scale
+				r.Deviation = math.Min(InitialDeviation, math.Sqrt(phi*phi+r.Volatility*r.Volatility)*glicko2Scale)
+				updated[name] = r
+			}
+		}
+	}
+
+	for name, r := range updated {
+		t.Ratings[name] = r
+	}
+}
+
+type opponent struct {
+	name  string
+	score float64
+	match int // Game.Match
+}
+
+// matches returns the number of matches the games against opps come from.
+func matches(opps []opponent) int {
+	n := 0
+	seen := map[int]bool{}
+	for _, o := range opps {
+		if o.match == 0 || !seen[o.match] {
+			n++
+		}
+		seen[o.match] = true
+	}
+	return n
+}
+
+func (t *Table) elo(r Rating, opps []opponent) Rating {
+	k := EloK / float64(len(opps))
+	delta := 0.0
+	for _, o := range opps {
+		other := t.Get(o.name)
+		expected := 1 / (1 + math.Pow(10, (other.Rating-r.Rating)/400))
+		delta += k * (o.score - expected)
+	}
+	r.Rating += delta
+	r.Games += matches(opps)
+	return r
+}
+
+// glicko2 implements one rating period of Glickman's Glicko-2 algorithm.
+func (t *Table) glicko2(r Rating, opps []opponent) Rating {
+	mu := (r.Rating - InitialRating) / glicko2Scale
+	phi := r.Deviation / glicko2Scale
+	sigma := r.Volatility
+
+	invV := 0.0
+	sum := 0.0
+	for _, o := range opps {
+		other := t.Get(o.name)
+		muJ := (other.Rating - InitialRating) / glicko2Scale
+		phiJ := other.Deviation / glicko2Scale
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		invV += g * g * e * (1 - e)
+		sum += g * (o.score - e)
+	}
+	v := 1 / invV
+	delta := v * sum
+
+	// Determine the new volatility with the Illinois algorithm.
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2 * math.Pow(phi*phi+v+ex, 2)
+		return num/den - (x-a)/(glicko2Tau*glicko2Tau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glicko2Tau) < 0 {
+			k++
+		}
+		B = a - k*glicko2Tau
+	}
+	fA := f(A)
+	fB := f(B)
+	for math.Abs(B-A) > glicko2Epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A = B
+			fA = fB
+		} else {
+			fA /= 2
+		}
+		B = C
+		fB = fC
+	}
+	newSigma := math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*sum
+
+	return Rating{
+		Rating:     newMu*glicko2Scale + InitialRating,
+		Deviation:  newPhi * glicko2Scale,
+		Volatility: newSigma,
+		Games:      r.Games + matches(opps),
+	}
+}
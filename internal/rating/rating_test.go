@@ -0,0 +1,124 @@
+package rating
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlicko2_PaperExample(t *testing.T) {
+	// Example from Glickman, "Example of the Glicko-2 system".
+	table := NewTable(Glicko2)
+	table.Ratings["player"] = Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	table.Ratings["a"] = Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}
+	table.Ratings["b"] = Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}
+	table.Ratings["c"] = Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}
+
+	table.Update([]Game{
+		{A: "player", B: "a", ScoreA: 1},
+		{A: "player", B: "b", ScoreA: 0},
+		{A: "c", B: "player", ScoreA: 1},
+	})
+
+	r := table.Ratings["player"]
+	if math.Abs(r.Rating-1464.06) > 0.05 {
+		t.Errorf("expected rating 1464.06, got %f", r.Rating)
+	}
+	if math.Abs(r.Deviation-151.52) > 0.05 {
+		t.Errorf("expected deviation 151.52, got %f", r.Deviation)
+	}
+	if math.Abs(r.Volatility-0.05999) > 0.00001 {
+		t.Errorf("expected volatility 0.05999, got %f", r.Volatility)
+	}
+	if r.Games != 3 {
+		t.Errorf("expected 3 games, got %d", r.Games)
+	}
+}
+
+func TestElo_OrderIndependent(t *testing.T) {
+	games := []Game{
+		{A: "x", B: "y", ScoreA: 1},
+		{A: "y", B: "z", ScoreA: 0.5},
+		{A: "z", B: "x", ScoreA: 1},
+	}
+	reversed := []Game{games[2], games[1], games[0]}
+
+	t1 := NewTable(Elo)
+	t1.Update(games)
+	t2 := NewTable(Elo)
+	t2.Update(reversed)
+
+	for _, name := range []string{"x", "y", "z"} {
+		if t1.Ratings[name] != t2.Ratings[name] {
+			t.Errorf("%s: expected identical ratings, got %+v and %+v", name, t1.Ratings[name], t2.Ratings[name])
+		}
+	}
+	if t1.Ratings["x"].Rating != InitialRating {
+		t.Errorf("expected x to stay at %v after a win and a loss against equals, got %f", InitialRating, t1.Ratings["x"].Rating)
+	}
+	// y lost one game and drew one; K is spread over its two games
+	if got := t1.Ratings["y"].Rating; got != InitialRating-EloK/4 {
+		t.Errorf("expected y rating %v, got %f", InitialRating-EloK/4, got)
+	}
+}
+
+func TestElo_PeriodMovesAtMostK(t *testing.T) {
+	var games []Game
+	for i := 0; i < 20; i++ {
+		games = append(games, Game{A: "x", B: "y", ScoreA: 1})
+	}
+	table := NewTable(Elo)
+	table.Update(games)
+
+	if got := table.Ratings["x"].Rating; got != InitialRating+EloK/2 {
+		t.Errorf("expected 20 wins against an equal to gain %v, got %f", EloK/2, got-InitialRating)
+	}
+	if got := table.Ratings["x"].Games; got != 20 {
+		t.Errorf("expected 20 games, got %d", got)
+	}
+}
+
+func TestUpdate_FreeForAllMatchCountsOnce(t *testing.T) {
+	// One four-bot match broken into pairwise games by finishing place
+	names := []string{"a", "b", "c", "d"}
+	var games []Game
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			games = append(games, Game{A: names[i], B: names[j], ScoreA: 1, Match: 1})
+		}
+	}
+	for _, system := range []System{Elo, Glicko2} {
+		table := NewTable(system)
+		table.Update(games)
+		for _, name := range names {
+			if got := table.Ratings[name].Games; got != 1 {
+				t.Errorf("%s: expected %s to count 1 game, got %d", system, name, got)
+			}
+		}
+	}
+}
+
+func TestLoadSave_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.json")
+
+	table, err := Load(path, Glicko2)
+	if err != nil {
+		t.Fatalf("expected missing file to yield empty table, got %v", err)
+	}
+	table.Update([]Game{{A: "a", B: "b", ScoreA: 1}})
+	if err := table.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path, Glicko2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Ratings["a"] != table.Ratings["a"] {
+		t.Errorf("expected %+v, got %+v", table.Ratings["a"], loaded.Ratings["a"])
+	}
+
+	if _, err := Load(path, Elo); err == nil {
+		t.Error("expected error when loading glicko2 ratings as elo")
+	}
+}
@@ -0,0 +1,466 @@
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// fuelExport is the name under which the metered module exports its fuel counter.
+const fuelExport = "__snowbot_fuel"
+
+// Section IDs of the WebAssembly binary format.
+const (
+	sectionCustom = 0
+	sectionImport = 2
+	sectionGlobal = 6
+	sectionExport = 7
+	sectionCode   = 10
+)
+
+// sectionOrder gives the position each known section must appear at in a module.
+var sectionOrder = map[byte]int{
+	1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 13: 6, 6: 7, 7: 8, 8: 9, 9: 10, 12: 11, 10: 12, 11: 13,
+}
+
+var errTruncated = errors.New("unexpected end of module")
+
+type section struct {
+	id      byte
+	payload []byte
+}
+
+// meter instruments a WebAssembly module so that every function call and every loop iteration
+// spends one unit of an exported i64 global (fuelExport). Once the fuel reaches zero the module
+// traps with "unreachable". Branches back to a loop header are how wasm code repeats, so this
+// counts the same kind of operations as the QuickJS interrupt counter and keeps the per-tick
+// budget deterministic.
+func meter(module []byte) ([]byte, error) {
+	if len(module) < 8 || !bytes.Equal(module[:4], []byte("\x00asm")) {
+		return nil, errors.New("not a WebAssembly module")
+	}
+	if !bytes.Equal(module[4:8], []byte{1, 0, 0, 0}) {
+		return nil, errors.New("unsupported WebAssembly version")
+	}
+
+	var sections []section
+	r := &reader{buf: module, pos: 8}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section{id: id, payload: payload})
+	}
+
+	// The fuel global is appended after the imported and defined globals
+	var importedGlobals, definedGlobals uint32
+	for _, s := range sections {
+		var err error
+		switch s.id {
+		case sectionImport:
+			importedGlobals, err = countImportedGlobals(s.payload)
+		case sectionGlobal:
+			definedGlobals, err = (&reader{buf: s.payload}).u32()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	fuel := importedGlobals + definedGlobals
+
+	// i64, mutable, initialized with an unlimited budget for start functions
+	global := []byte{0x7E, 0x01, 0x42}
+	global = appendS64(global, math.MaxInt64)
+	global = append(global, 0x0B)
+
+	export := appendU32(nil, uint32(len(fuelExport)))
+	export = append(export, fuelExport...)
+	export = append(export, 0x03)
+	export = appendU32(export, fuel)
+
+	check := fuelCheck(fuel)
+
+	hasGlobal, hasExport := false, false
+	for i := range sections {
+		s := &sections[i]
+		var err error
+		switch s.id {
+		case sectionGlobal:
+			hasGlobal = true
+			s.payload, err = appendEntry(s.payload, global)
+		case sectionExport:
+			hasExport = true
+			s.payload, err = appendEntry(s.payload, export)
+		case sectionCode:
+			s.payload, err = meterCode(s.payload, check)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !hasGlobal {
+		sections = insertSection(sections, section{id: sectionGlobal, payload: append([]byte{1}, global...)})
+	}
+	if !hasExport {
+		sections = insertSection(sections, section{id: sectionExport, payload: append([]byte{1}, export...)})
+	}
+
+	out := append([]byte(nil), module[:8]...)
+	for _, s := range sections {
+		out = append(out, s.id)
+		out = appendU32(out, uint32(len(s.payload)))
+		out = append(out, s.payload...)
+	}
+	return out, nil
+}
+
+// fuelCheck returns the code that traps when the fuel global is used up and spends one unit otherwise.
+func fuelCheck(global uint32) []byte {
+	var code []byte
+	code = append(code, 0x23) // global.get
+	code = appendU32(code, global)
+	code = append(code, 0x50, 0x04, 0x40, 0x00, 0x0B) // i64.eqz if unreachable end
+	code = append(code, 0x23)                         // global.get
+	code = appendU32(code, global)
+	code = append(code, 0x42, 0x01, 0x7D, 0x24) // i64.const 1 i64.sub global.set
+	code = appendU32(code, global)
+	return code
+}
+
+// insertSection adds a section at the position the binary format requires.
+func insertSection(sections []section, s section) []section {
+	for i, existing := range sections {
+		if existing.id != sectionCustom && sectionOrder[existing.id] > sectionOrder[s.id] {
+			return append(sections[:i], append([]section{s}, sections[i:]...)...)
+		}
+	}
+	return append(sections, s)
+}
+
+// appendEntry adds an entry to a section made of a vector of entries.
+func appendEntry(payload, entry []byte) ([]byte, error) {
+	r := &reader{buf: payload}
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count+1)
+	out = append(out, payload[r.pos:]...)
+	return append(out, entry...), nil
+}
+
+func countImportedGlobals(payload []byte) (uint32, error) {
+	r := &reader{buf: payload}
+	count, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	var globals uint32
+	for i := uint32(0); i < count; i++ {
+		// module and field names
+		for j := 0; j < 2; j++ {
+			n, err := r.u32()
+			if err != nil {
+				return 0, err
+			}
+			if _, err := r.bytes(int(n)); err != nil {
+				return 0, err
+			}
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		switch kind {
+		case 0x00: // function
+			_, err = r.u32()
+		case 0x01: // table
+			if _, err = r.byte(); err == nil {
+				err = r.limits()
+			}
+		case 0x02: // memory
+			err = r.limits()
+		case 0x03: // global
+			globals++
+			_, err = r.bytes(2)
+		default:
+			err = fmt.Errorf("unsupported import kind 0x%02x", kind)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return globals, nil
+}
+
+// meterCode inserts check at the start of every function body and of every loop.
+func meterCode(payload, check []byte) ([]byte, error) {
+	r := &reader{buf: payload}
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count)
+	for i := uint32(0); i < count; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		metered, err := meterBody(body, check)
+		if err != nil {
+			return nil, fmt.Errorf("function %d: %w", i, err)
+		}
+		out = appendU32(out, uint32(len(metered)))
+		out = append(out, metered...)
+	}
+	return out, nil
+}
+
+func meterBody(body, check []byte) ([]byte, error) {
+	r := &reader{buf: body}
+	locals, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < locals; i++ {
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		if _, err := r.byte(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := append([]byte(nil), body[:r.pos]...)
+	out = append(out, check...)
+	start := r.pos
+	for !r.done() {
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.skipImmediates(op); err != nil {
+			return nil, err
+		}
+		if op == 0x03 { // loop
+			out = append(out, body[start:r.pos]...)
+			out = append(out, check...)
+			start = r.pos
+		}
+	}
+	return append(out, body[start:]...), nil
+}
+
+// reader decodes the parts of the binary format the meter needs.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.done() {
+		return 0, errTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.buf) {
+		return nil, errTruncated
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	var v uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("invalid LEB128 integer")
+}
+
+// skipLEB skips a signed or unsigned LEB128 integer.
+func (r *reader) skipLEB() error {
+	for i := 0; i < 10; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b&0x80 == 0 {
+			return nil
+		}
+	}
+	return errors.New("invalid LEB128 integer")
+}
+
+func (r *reader) limits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if err := r.skipLEB(); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		return r.skipLEB()
+	}
+	return nil
+}
+
+// skipLEBs skips n LEB128 integers.
+func (r *reader) skipLEBs(n int) error {
+	for i := 0; i < n; i++ {
+		if err := r.skipLEB(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipImmediates skips the immediates of an instruction of the WebAssembly 2.0 core specification.
+func (r *reader) skipImmediates(op byte) error {
+	switch {
+	case op == 0x02 || op == 0x03 || op == 0x04: // block, loop, if: block type
+		return r.skipLEB()
+	case op == 0x0C || op == 0x0D: // br, br_if
+		return r.skipLEB()
+	case op == 0x0E: // br_table
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		return r.skipLEBs(int(n) + 1)
+	case op == 0x10: // call
+		return r.skipLEB()
+	case op == 0x11: // call_indirect
+		return r.skipLEBs(2)
+	case op == 0x1C: // select with types
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		_, err = r.bytes(int(n))
+		return err
+	case op >= 0x20 && op <= 0x26: // local, global and table get/set
+		return r.skipLEB()
+	case op >= 0x28 && op <= 0x3E: // loads and stores: memarg
+		return r.skipLEBs(2)
+	case op == 0x3F || op == 0x40: // memory.size, memory.grow
+		return r.skipLEB()
+	case op == 0x41 || op == 0x42: // i32.const, i64.const
+		return r.skipLEB()
+	case op == 0x43: // f32.const
+		_, err := r.bytes(4)
+		return err
+	case op == 0x44: // f64.const
+		_, err := r.bytes(8)
+		return err
+	case op == 0xD0: // ref.null
+		_, err := r.byte()
+		return err
+	case op == 0xD2: // ref.func
+		return r.skipLEB()
+	case op == 0xFC:
+		return r.skipMiscImmediates()
+	case op == 0xFD:
+		return r.skipVectorImmediates()
+	case op <= 0x01 || op == 0x05 || op == 0x0B || op == 0x0F || op == 0x1A || op == 0x1B ||
+		(op >= 0x45 && op <= 0xC4) || op == 0xD1:
+		return nil
+	}
+	return fmt.Errorf("unsupported instruction 0x%02x", op)
+}
+
+// skipMiscImmediates skips the immediates of a 0xFC-prefixed instruction.
+func (r *reader) skipMiscImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub <= 7: // saturating truncation
+		return nil
+	case sub == 9 || sub == 11 || sub == 13 || (sub >= 15 && sub <= 17):
+		return r.skipLEB()
+	case sub == 8 || sub == 10 || sub == 12 || sub == 14:
+		return r.skipLEBs(2)
+	}
+	return fmt.Errorf("unsupported instruction 0xfc %d", sub)
+}
+
+// skipVectorImmediates skips the immediates of a 0xFD-prefixed (SIMD) instruction.
+func (r *reader) skipVectorImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub <= 11 || sub == 92 || sub == 93: // loads and stores: memarg
+		return r.skipLEBs(2)
+	case sub == 12 || sub == 13: // v128.const, i8x16.shuffle
+		_, err := r.bytes(16)
+		return err
+	case sub >= 21 && sub <= 34: // extract and replace lane
+		_, err := r.byte()
+		return err
+	case sub >= 84 && sub <= 91: // lane loads and stores: memarg and lane
+		if err := r.skipLEBs(2); err != nil {
+			return err
+		}
+		_, err := r.byte()
+		return err
+	}
+	return nil
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v != 0 {
+			b = append(b, c|0x80)
+			continue
+		}
+		return append(b, c)
+	}
+}
+
+func appendS64(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
@@ -0,0 +1,458 @@
+package wasm
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/js"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// pageSize is the size of a WebAssembly memory page.
+const pageSize = 65536
+
+// Sizes of the records scan and scan_snowballs write to memory.
+const (
+	scanRecordSize     = 40
+	snowballRecordSize = 32
+)
+
+// Object types reported in scan records.
+const (
+	objectSnowbot  = 1
+	objectObstacle = 2
+	objectSnowball = 3
+	objectPile     = 4
+)
+
+// Flags reported in scan records.
+const (
+	flagAlly      = 1 << 0
+	flagDetail    = 1 << 1
+	flagReloading = 1 << 2
+)
+
+func init() {
+	factory := func(cfg *config.Config, playerID int) js.Runtime {
+		return NewRuntime(cfg, playerID)
+	}
+	js.Register(".wasm", factory)
+	js.RegisterScheme("wasm", factory)
+}
+
+// Runtime implements js.Runtime for WebAssembly bots using the pure-Go wazero interpreter.
+// Bots import the SnowBot API from the "snowbot" module and export a "run" function that
+// takes no arguments.
+type Runtime struct {
+	Config *config.Config
+	bot    *js.API
+
+	ctx      context.Context
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	module   api.Module
+}
+
+// NewRuntime creates a new WebAssembly Runtime instance.
+func NewRuntime(cfg *config.Config, playerID int) *Runtime {
+	return &Runtime{
+		Config: cfg,
+		bot:    js.NewAPI(cfg, playerID),
+		ctx:    context.Background(),
+	}
+}
+
+func (rt *Runtime) Close() {
+	if rt.runtime != nil {
+		rt.runtime.Close(rt.ctx)
+	}
+}
+
+// Deliver sets the radio messages receive returns during the next Run.
+func (rt *Runtime) Deliver(messages []js.RadioMessage) {
+	rt.bot.Deliver(messages)
+}
+
+// Sent returns the radio messages sent during the last Run.
+func (rt *Runtime) Sent() []js.RadioMessage {
+	return rt.bot.Sent()
+}
+
+// SetStorage enables storage_get/storage_set with the given stored value (nil for none).
+// Call it before Load so the start function of the module can read it.
+func (rt *Runtime) SetStorage(data json.RawMessage) {
+	rt.bot.SetStorage(data)
+}
+
+// Storage returns the value stored with storage_set, or nil if there is none.
+func (rt *Runtime) Storage() json.RawMessage {
+	return rt.bot.Storage()
+}
+
+// Load compiles and instantiates the WebAssembly module, running its start functions.
+func (rt *Runtime) Load(code string) error {
+	metered, err := meter([]byte(code))
+	if err != nil {
+		return fmt.Errorf("invalid WebAssembly module: %w", err)
+	}
+
+	// Configure resource limits. The instruction budget interrupts the module without losing its
+	// state; only without a budget does the wall-clock timeout have to close the instance.
+	runtimeConfig := wazero.NewRuntimeConfigInterpreter()
+	if rt.wallClockCloses() {
+		runtimeConfig = runtimeConfig.WithCloseOnContextDone(true)
+	}
+	if rt.Config.Runtime.WasmMaxMemoryBytes > 0 {
+		pages := rt.Config.Runtime.WasmMaxMemoryBytes / pageSize
+		if pages < 1 {
+			pages = 1
+		}
+		if pages < math.MaxUint16+1 {
+			runtimeConfig = runtimeConfig.WithMemoryLimitPages(uint32(pages))
+		}
+	}
+	rt.runtime = wazero.NewRuntimeWithConfig(rt.ctx, runtimeConfig)
+
+	if err := rt.registerBuiltins(); err != nil {
+		return err
+	}
+	rt.compiled, err = rt.runtime.CompileModule(rt.ctx, metered)
+	if err != nil {
+		return err
+	}
+	return rt.instantiate()
+}
+
+// wallClockCloses reports whether the wall-clock timeout closes the module instance, which is
+// the case only when the instruction budget is disabled.
+func (rt *Runtime) wallClockCloses() bool {
+	return rt.Config.Runtime.MaxInstructionsPerTick <= 0 && rt.Config.Runtime.TickTimeoutMs > 0
+}
+
+// instantiate creates a fresh instance of the compiled module.
+func (rt *Runtime) instantiate() error {
+	moduleConfig := wazero.NewModuleConfig().WithName("bot").WithStartFunctions("_initialize", "_start")
+	module, err := rt.runtime.InstantiateModule(rt.ctx, rt.compiled, moduleConfig)
+	if err != nil {
+		return err
+	}
+	rt.module = module
+	return nil
+}
+
+// Run executes the exported 'run' function of the module.
+func (rt *Runtime) Run(state game.GameState) ([]game.Action, []js.Warning, error) {
+	// Reset actions for this tick and store the state for API functions to access
+	rt.bot.BeginTick(&state)
+
+	if rt.module == nil || rt.module.IsClosed() {
+		// A wall-clock timeout closes the instance; continue with a fresh one
+		if err := rt.instantiate(); err != nil {
+			return nil, rt.bot.Warnings(), fmt.Errorf("failed to restart module: %w", err)
+		}
+		rt.bot.Warn("module restarted after a timeout, its state was reset", "run", nil)
+	}
+
+	runFn := rt.module.ExportedFunction("run")
+	if runFn == nil {
+		return nil, rt.bot.Warnings(), fmt.Errorf("run is not exported")
+	}
+	fuel, ok := rt.module.ExportedGlobal(fuelExport).(api.MutableGlobal)
+	if !ok {
+		return nil, rt.bot.Warnings(), fmt.Errorf("module is not metered")
+	}
+
+	// The instruction budget is counted by the metering code, which makes it reproducible
+	// across machines. The millisecond timeout is only a backstop when there is no budget.
+	budget := rt.Config.Runtime.MaxInstructionsPerTick
+	if budget > 0 {
+		fuel.Set(uint64(budget))
+	} else {
+		fuel.Set(math.MaxInt64)
+	}
+	ctx := rt.ctx
+	if rt.wallClockCloses() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(rt.Config.Runtime.TickTimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	_, err := runFn.Call(ctx)
+	if err == nil {
+		return rt.bot.Actions(), rt.bot.Warnings(), nil
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		used := 0
+		if budget > 0 {
+			used = budget - int(fuel.Get())
+		}
+		rt.bot.WarnTimeout(js.LimitWallClock, used, budget)
+	case budget > 0 && fuel.Get() == 0:
+		rt.bot.WarnTimeout(js.LimitInstructions, budget, budget)
+	default:
+		// Traps carry a wasm stack trace on the following lines
+		msg, _, _ := strings.Cut(err.Error(), "\n")
+		rt.bot.Warn(msg, "run", nil)
+	}
+	return rt.bot.Actions(), rt.bot.Warnings(), nil
+}
+
+func (rt *Runtime) registerBuiltins() error {
+	bot := rt.bot
+	builder := rt.runtime.NewHostModuleBuilder("snowbot")
+	export := func(name string, fn interface{}) {
+		builder.NewFunctionBuilder().WithFunc(fn).Export(name)
+	}
+
+	// move(distance)
+	export("move", func(ctx context.Context, m api.Module, distance int32) {
+		if bot.FirstCall("move", warningArgs(distance)) {
+			bot.Move(int(distance), warningArgs(distance))
+		}
+	})
+
+	// turn(degrees)
+	export("turn", func(ctx context.Context, m api.Module, angle int32) {
+		if bot.FirstCall("turn", warningArgs(angle)) {
+			bot.Turn(int(angle), warningArgs(angle))
+		}
+	})
+
+	// toss(distance)
+	export("toss", func(ctx context.Context, m api.Module, distance int32) {
+		if bot.FirstCall("toss", warningArgs(distance)) {
+			bot.Toss(int(distance), warningArgs(distance))
+		}
+	})
+
+	// make_snowball()
+	export("make_snowball", func(ctx context.Context, m api.Module) {
+		if bot.FirstCall("make_snowball", nil) {
+			bot.MakeSnowball(nil)
+		}
+	})
+
+	// scan(angle, resolution, ptr, max) -> number of objects detected
+	export("scan", func(ctx context.Context, m api.Module, angle, resolution int32, ptr, max uint32) int32 {
+		args := warningArgs(angle, resolution, ptr, max)
+		results := bot.Scan(int(angle), int(resolution), args)
+		buf := make([]byte, 0, scanRecordSize*len(results))
+		for i, obj := range results {
+			if uint32(i) >= max {
+				break
+			}
+			buf = appendScanRecord(buf, obj)
+		}
+		if !rt.write(m, ptr, buf, "scan", args) {
+			return 0
+		}
+		return int32(len(results))
+	})
+
+	// scan_snowballs(angle, resolution, ptr, max) -> number of snowballs detected
+	export("scan_snowballs", func(ctx context.Context, m api.Module, angle, resolution int32, ptr, max uint32) int32 {
+		args := warningArgs(angle, resolution, ptr, max)
+		results := bot.ScanSnowballs(int(angle), int(resolution), args)
+		buf := make([]byte, 0, snowballRecordSize*len(results))
+		for i, obj := range results {
+			if uint32(i) >= max {
+				break
+			}
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Angle))
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Distance))
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Heading))
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Speed))
+		}
+		if !rt.write(m, ptr, buf, "scan_snowballs", args) {
+			return 0
+		}
+		return int32(len(results))
+	})
+
+	// send(channel_ptr, channel_len, message_ptr, message_len)
+	export("send", func(ctx context.Context, m api.Module, channelPtr, channelLen, messagePtr, messageLen uint32) {
+		args := warningArgs(channelPtr, channelLen, messagePtr, messageLen)
+		channel, ok := rt.read(m, channelPtr, channelLen, "send", args)
+		if !ok {
+			return
+		}
+		message, ok := rt.read(m, messagePtr, messageLen, "send", args)
+		if !ok {
+			return
+		}
+		bot.Send(string(channel), string(message), args)
+	})
+
+	// receive(channel_ptr, channel_len, ptr, max) -> length of the JSON array of messages
+	export("receive", func(ctx context.Context, m api.Module, channelPtr, channelLen, ptr, max uint32) int32 {
+		args := warningArgs(channelPtr, channelLen, ptr, max)
+		channel, ok := rt.read(m, channelPtr, channelLen, "receive", args)
+		if !ok {
+			return 0
+		}
+		resultsJSON, _ := json.Marshal(bot.Receive(string(channel)))
+		return rt.writeJSON(m, ptr, max, resultsJSON, "receive", args)
+	})
+
+	// storage_get(ptr, max) -> length of the stored JSON value
+	export("storage_get", func(ctx context.Context, m api.Module, ptr, max uint32) int32 {
+		value := bot.Storage()
+		if value == nil {
+			value = json.RawMessage("null")
+		}
+		return rt.writeJSON(m, ptr, max, value, "storage_get", warningArgs(ptr, max))
+	})
+
+	// storage_set(ptr, len)
+	export("storage_set", func(ctx context.Context, m api.Module, ptr, length uint32) {
+		args := warningArgs(ptr, length)
+		value, ok := rt.read(m, ptr, length, "storage.set", args)
+		if !ok {
+			return
+		}
+		bot.StorageSet(string(value), args)
+	})
+
+	// log(ptr, len)
+	export("log", func(ctx context.Context, m api.Module, ptr, length uint32) {
+		if m.Memory() == nil {
+			return
+		}
+		if text, ok := m.Memory().Read(ptr, length); ok {
+			fmt.Fprintln(os.Stderr, string(text))
+		}
+	})
+
+	// State getters
+	player := func() *game.Player {
+		if p := bot.Player(); p != nil {
+			return p
+		}
+		return &game.Player{}
+	}
+	export("tick", func(ctx context.Context, m api.Module) int32 { return int32(bot.Tick()) })
+	export("position_x", func(ctx context.Context, m api.Module) float64 { return player().X })
+	export("position_y", func(ctx context.Context, m api.Module) float64 { return player().Y })
+	export("direction", func(ctx context.Context, m api.Module) int32 { return int32(player().Angle) })
+	export("hp", func(ctx context.Context, m api.Module) int32 { return int32(player().HP) })
+	export("max_hp", func(ctx context.Context, m api.Module) int32 { return int32(rt.Config.Snowbot.MaxHP) })
+	export("snowball_count", func(ctx context.Context, m api.Module) int32 { return int32(player().SnowballCount) })
+	export("max_snowball", func(ctx context.Context, m api.Module) int32 { return int32(rt.Config.Snowbot.MaxSnowball) })
+	export("gather_ticks", func(ctx context.Context, m api.Module) int32 { return int32(player().GatherTicks) })
+	export("team", func(ctx context.Context, m api.Module) int32 { return int32(player().Team) })
+	export("speed", func(ctx context.Context, m api.Module) float64 { return player().Speed })
+	export("energy", func(ctx context.Context, m api.Module) float64 { return bot.Energy() })
+	export("max_energy", func(ctx context.Context, m api.Module) float64 { return bot.MaxEnergy() })
+
+	// The zone getters return -1 for the radius when the zone is disabled
+	zone := func() *js.ZoneInfo {
+		if z := bot.Zone(); z != nil {
+			return z
+		}
+		return &js.ZoneInfo{Radius: -1, FinalRadius: -1}
+	}
+	export("zone_x", func(ctx context.Context, m api.Module) float64 { return zone().X })
+	export("zone_y", func(ctx context.Context, m api.Module) float64 { return zone().Y })
+	export("zone_radius", func(ctx context.Context, m api.Module) float64 { return zone().Radius })
+	export("zone_final_radius", func(ctx context.Context, m api.Module) float64 { return zone().FinalRadius })
+	export("zone_shrink_per_tick", func(ctx context.Context, m api.Module) float64 { return zone().ShrinkPerTick })
+	export("zone_start_tick", func(ctx context.Context, m api.Module) int32 { return int32(zone().StartTick) })
+
+	_, err := builder.Instantiate(rt.ctx)
+	return err
+}
+
+// read returns length bytes of the module memory at ptr, warning when the range is invalid.
+func (rt *Runtime) read(m api.Module, ptr, length uint32, name string, args []interface{}) ([]byte, bool) {
+	if m.Memory() != nil {
+		if data, ok := m.Memory().Read(ptr, length); ok {
+			return data, true
+		}
+	}
+	rt.bot.Warn("invalid memory range", name, args)
+	return nil, false
+}
+
+// write copies data to the module memory at ptr, warning when the range is invalid.
+func (rt *Runtime) write(m api.Module, ptr uint32, data []byte, name string, args []interface{}) bool {
+	if len(data) == 0 {
+		return true
+	}
+	if m.Memory() != nil && m.Memory().Write(ptr, data) {
+		return true
+	}
+	rt.bot.Warn("invalid memory range", name, args)
+	return false
+}
+
+// writeJSON copies as much of data as fits in max bytes at ptr and returns the full length,
+// so a bot can retry with a larger buffer.
+func (rt *Runtime) writeJSON(m api.Module, ptr, max uint32, data []byte, name string, args []interface{}) int32 {
+	if uint32(len(data)) < max {
+		max = uint32(len(data))
+	}
+	if !rt.write(m, ptr, data[:max], name, args) {
+		return 0
+	}
+	return int32(len(data))
+}
+
+// appendScanRecord appends the little-endian scan record of obj:
+// type i32, flags i32, angle f64, distance f64, id i32 (amount for piles), hp_bucket i32, heading f64.
+func appendScanRecord(buf []byte, obj game.FieldObject) []byte {
+	var kind, flags uint32
+	switch obj.Type {
+	case "snowbot":
+		kind = objectSnowbot
+	case "obstacle":
+		kind = objectObstacle
+	case "snowball":
+		kind = objectSnowball
+	case "pile":
+		kind = objectPile
+	}
+	if obj.Ally {
+		flags |= flagAlly
+	}
+	detail := game.ScanDetail{}
+	if obj.ScanDetail != nil {
+		detail = *obj.ScanDetail
+		flags |= flagDetail
+		if detail.Reloading {
+			flags |= flagReloading
+		}
+	}
+	if kind == objectPile {
+		// Piles report their amount in the id field
+		detail.ID = obj.Amount
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, kind)
+	buf = binary.LittleEndian.AppendUint32(buf, flags)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Angle))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(obj.Distance))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(detail.ID))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(detail.HPBucket))
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(detail.Heading))
+}
+
+// warningArgs converts call arguments to the strings reported in warnings.
+func warningArgs(args ...interface{}) []interface{} {
+	converted := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		converted = append(converted, fmt.Sprint(a))
+	}
+	return converted
+}
+
+var _ js.Runtime = (*Runtime)(nil)
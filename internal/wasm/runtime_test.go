@@ -0,0 +1,231 @@
+package wasm
+
+import (
+	"snowfight/internal/config"
+	"snowfight/internal/game"
+	"snowfight/internal/js"
+	"strings"
+	"testing"
+)
+
+// buildModule assembles a module importing the given (i32) -> () functions from "snowbot"
+// and exporting a "run" function with the given body (which must end with 0x0B).
+func buildModule(imports []string, memoryPages uint32, body []byte) []byte {
+	sec := func(id byte, payload []byte) []byte {
+		return append(appendU32([]byte{id}, uint32(len(payload))), payload...)
+	}
+	name := func(b []byte, s string) []byte {
+		return append(appendU32(b, uint32(len(s))), s...)
+	}
+
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	// type 0: (i32) -> (), type 1: () -> ()
+	module = append(module, sec(1, []byte{2, 0x60, 1, 0x7F, 0, 0x60, 0, 0})...)
+
+	importSec := appendU32(nil, uint32(len(imports)))
+	for _, imp := range imports {
+		importSec = name(importSec, "snowbot")
+		importSec = name(importSec, imp)
+		importSec = append(importSec, 0x00, 0x00)
+	}
+	module = append(module, sec(2, importSec)...)
+	module = append(module, sec(3, []byte{1, 1})...)
+	if memoryPages > 0 {
+		module = append(module, sec(5, appendU32([]byte{1, 0x00}, memoryPages))...)
+	}
+
+	exportSec := name([]byte{1}, "run")
+	exportSec = appendU32(append(exportSec, 0x00), uint32(len(imports)))
+	module = append(module, sec(7, exportSec)...)
+
+	code := append([]byte{0}, body...) // no locals
+	module = append(module, sec(10, append(appendU32([]byte{1}, uint32(len(code))), code...))...)
+	return module
+}
+
+// call returns the code calling import fn with an i32 argument.
+func call(fn uint32, arg int64) []byte {
+	code := appendS64([]byte{0x41}, arg)
+	return appendU32(append(code, 0x10), fn)
+}
+
+func TestRun_Actions(t *testing.T) {
+	cfg := config.Default()
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	var body []byte
+	body = append(body, call(0, 10)...) // move(10)
+	body = append(body, call(1, 90)...) // turn(90)
+	body = append(body, call(2, 50)...) // toss(50)
+	body = append(body, call(0, 5)...)  // move(5) again
+	body = append(body, 0x0B)
+	if err := rt.Load(string(buildModule([]string{"move", "turn", "toss"}, 0, body))); err != nil {
+		t.Fatal(err)
+	}
+
+	state := game.GameState{Players: []game.Player{{HP: 5, SnowballCount: 5}}}
+	actions, warnings, err := rt.Run(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions, got %d", len(actions))
+	}
+	if actions[0].Type != game.ActionMove || actions[0].Value != 10 {
+		t.Errorf("expected move 10, got %+v", actions[0])
+	}
+	if actions[1].Type != game.ActionTurn || actions[1].Value != 90 {
+		t.Errorf("expected turn 90, got %+v", actions[1])
+	}
+	if actions[2].Type != game.ActionToss || actions[2].ThrowDistance != 50 {
+		t.Errorf("expected toss 50, got %+v", actions[2])
+	}
+
+	if len(warnings) != 1 || warnings[0].Warning != "called multiple times in one tick" || warnings[0].API != "move" {
+		t.Fatalf("expected a single duplicate move warning, got %+v", warnings)
+	}
+}
+
+func TestRun_InstructionBudget(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.MaxInstructionsPerTick = 1000
+	cfg.Runtime.TickTimeoutMs = 10000
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	// loop br 0 end: never returns
+	if err := rt.Load(string(buildModule(nil, 0, []byte{0x03, 0x40, 0x0C, 0x00, 0x0B, 0x0B}))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The instance keeps running on later ticks with a fresh budget
+	for tick := 1; tick <= 2; tick++ {
+		_, warnings, err := rt.Run(game.GameState{Tick: tick})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) != 1 || warnings[0].Warning != "execution timed out" {
+			t.Fatalf("tick %d: expected timeout warning, got %+v", tick, warnings)
+		}
+		if warnings[0].Limit != js.LimitInstructions || warnings[0].InstructionBudget != 1000 {
+			t.Errorf("tick %d: expected instruction limit with budget 1000, got %+v", tick, warnings[0])
+		}
+	}
+}
+
+// counterBody increments the i32 at address 0, calls move with it and then loops forever.
+var counterBody = []byte{
+	0x41, 0x00, 0x41, 0x00, 0x28, 0x02, 0x00, 0x41, 0x01, 0x6A, 0x36, 0x02, 0x00, // mem[0] = mem[0] + 1
+	0x41, 0x00, 0x28, 0x02, 0x00, 0x10, 0x00, // move(mem[0])
+	0x03, 0x40, 0x0C, 0x00, 0x0B, // loop br 0 end
+	0x0B,
+}
+
+func TestRun_StateKeptAfterInstructionLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.MaxInstructionsPerTick = 1000
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(string(buildModule([]string{"move"}, 1, counterBody))); err != nil {
+		t.Fatal(err)
+	}
+	for tick := 1; tick <= 2; tick++ {
+		actions, warnings, err := rt.Run(game.GameState{Tick: tick, Players: []game.Player{{HP: 5}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actions) != 1 || actions[0].Value != float64(tick) {
+			t.Errorf("tick %d: expected move %d from the kept memory, got %+v", tick, tick, actions)
+		}
+		if len(warnings) != 1 || warnings[0].Limit != js.LimitInstructions {
+			t.Errorf("tick %d: expected an instruction limit warning, got %+v", tick, warnings)
+		}
+	}
+}
+
+func TestRun_WallClockRestartWarns(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.MaxInstructionsPerTick = 0
+	cfg.Runtime.TickTimeoutMs = 20
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(string(buildModule([]string{"move"}, 1, counterBody))); err != nil {
+		t.Fatal(err)
+	}
+	_, warnings, err := rt.Run(game.GameState{Tick: 1, Players: []game.Player{{HP: 5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Limit != js.LimitWallClock {
+		t.Fatalf("expected a wall-clock timeout warning, got %+v", warnings)
+	}
+
+	// Without an instruction budget the timeout closes the instance, so the memory starts over
+	actions, warnings, err := rt.Run(game.GameState{Tick: 2, Players: []game.Player{{HP: 5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Value != 1 {
+		t.Errorf("expected move 1 from a fresh instance, got %+v", actions)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0].Warning, "module restarted") {
+		t.Errorf("expected a restart warning before the timeout, got %+v", warnings)
+	}
+}
+
+func TestRun_Trap(t *testing.T) {
+	cfg := config.Default()
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(string(buildModule(nil, 0, []byte{0x00, 0x0B}))); err != nil {
+		t.Fatal(err)
+	}
+
+	_, warnings, err := rt.Run(game.GameState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].API != "run" || !strings.Contains(warnings[0].Warning, "unreachable") {
+		t.Fatalf("expected unreachable trap warning, got %+v", warnings)
+	}
+}
+
+func TestLoad_MemoryLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Runtime.WasmMaxMemoryBytes = 4 * pageSize
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(string(buildModule(nil, 8, []byte{0x0B}))); err == nil {
+		t.Fatal("expected an error for a memory larger than wasm_max_memory_bytes")
+	}
+}
+
+func TestLoad_MemoryLimitFitsCompilerOutput(t *testing.T) {
+	// Rust's wasm32 target declares 17 pages of initial memory (a 1 MiB stack plus data),
+	// more than the 512 KB JavaScript limit of config.toml
+	cfg := config.Default()
+	cfg.Runtime.MaxMemoryBytes = 524288
+	rt := NewRuntime(cfg, 1)
+	defer rt.Close()
+
+	if err := rt.Load(string(buildModule(nil, 17, []byte{0x0B}))); err != nil {
+		t.Fatalf("expected a 17-page module to load, got %v", err)
+	}
+}
+
+func TestNewRuntime_Registered(t *testing.T) {
+	rt, err := js.NewRuntime("bots/bot.wasm", config.Default(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	if _, ok := rt.(*Runtime); !ok {
+		t.Fatalf("expected a WebAssembly runtime, got %T", rt)
+	}
+}
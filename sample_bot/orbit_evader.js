@@ -1,5 +1,6 @@
 // CROBOTS-inspired evasive bot.
 // Strategy:
+// - Watch for incoming snowballs and sidestep them.
 // - Keep orbiting by combining short forward moves with small right turns.
 // - If an enemy is almost straight ahead (within +-15°) and medium range, take a shot.
 
@@ -15,9 +16,32 @@ function deltaAngle(target, current) {
   return diff; // [-180,180]
 }
 
+// Returns the closest snowball flying towards us, or null.
+function incomingSnowball() {
+  let closest = null;
+  for (let a = 0; a < 360; a += 45) {
+    for (const sb of scan_snowballs(a, 45)) {
+      // A snowball heading back along its bearing is flying at us.
+      const approach = Math.abs(deltaAngle(sb.heading, sb.angle + 180));
+      if (approach <= 20 && sb.distance <= 60 && (!closest || sb.distance < closest.distance)) {
+        closest = sb;
+      }
+    }
+  }
+  return closest;
+}
+
 function run(state) {
+  const threat = incomingSnowball();
+  if (threat) {
+    // Step sideways out of the snowball's path.
+    turn(deltaAngle(threat.heading + 90, direction()));
+    move(10);
+    return;
+  }
+
   const resolution = 30;
-  const results = scan(direction(), resolution);
+  const results = scan(direction(), resolution).filter((o) => o.type === "snowbot" && !o.ally);
 
   if (results.length > 0) {
     const target = results[0];
@@ -23,7 +23,7 @@ function deltaAngle(target, current) {
 
 function run(state) {
   const resolution = 45;
-  const results = scan(scanAngle, resolution);
+  const results = scan(scanAngle, resolution).filter((o) => o.type === "snowbot" && !o.ally);
 
   // advance scan angle for next tick
   scanAngle = normalize(scanAngle + sweepStep);
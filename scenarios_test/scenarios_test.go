@@ -2,6 +2,7 @@ package scenarios_test
 
 import (
 	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
 	"snowfight/internal/config"
@@ -48,25 +49,8 @@ func runScenario(t *testing.T, scenarioDir string) []game.GameState {
 		t.Fatalf("failed to load p2.js: %v", err)
 	}
 
-	// Create game engine
+	// Create game engine (scenarios declare their start positions with match.spawn_points)
 	engine := game.NewGame(cfg, numPlayers)
-	// For deterministic scenario tests, override spawn to fixed legacy positions
-	engine.State.Players[0] = game.Player{
-		X:             -50,
-		Y:             0,
-		HP:            cfg.Snowbot.MaxHP,
-		Angle:         0,
-		SnowballCount: cfg.Snowbot.MaxSnowball,
-	}
-	engine.State.Players[1] = game.Player{
-		X:             50,
-		Y:             0,
-		HP:            cfg.Snowbot.MaxHP,
-		Angle:         180,
-		SnowballCount: cfg.Snowbot.MaxSnowball,
-	}
-	engine.State.P1 = engine.State.Players[0]
-	engine.State.P2 = engine.State.Players[1]
 
 	// Run game and collect states
 	states := []game.GameState{engine.State}
@@ -436,3 +420,47 @@ func TestScenario10_OOMWarning(t *testing.T) {
 		t.Fatalf("expected OOM warning for player 1, got %+v %+v", warnings1, warnings2)
 	}
 }
+
+// diagonalEnd is the exact position after P1 turns to 30° and moves 7 for 20 ticks from (-50, 0).
+func diagonalEnd() (float64, float64) {
+	rad := 30 * math.Pi / 180
+	return -50 + 140*math.Sin(rad), 140 * math.Cos(rad)
+}
+
+func TestScenario11_DiagonalPrecision(t *testing.T) {
+	states := runScenario(t, "testdata/scenarios/11_diagonal_precision")
+
+	wantX, wantY := diagonalEnd()
+	final := states[len(states)-1].P1
+	if math.Abs(final.X-wantX) > 0.01 || math.Abs(final.Y-wantY) > 0.01 {
+		t.Errorf("expected P1 within 0.01 of (%.3f, %.3f), got (%f, %f)", wantX, wantY, final.X, final.Y)
+	}
+
+	// Positions keep 3 decimal places
+	for i, s := range states {
+		for _, v := range []float64{s.P1.X, s.P1.Y} {
+			if scaled := v * 1000; math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+				t.Fatalf("tick %d: expected 3 decimal places, got %v", i, v)
+			}
+		}
+	}
+}
+
+func TestScenario12_DiagonalIntegerDrift(t *testing.T) {
+	states := runScenario(t, "testdata/scenarios/12_diagonal_integer")
+
+	// Every step of 7·cos30° ≈ 6.062 north is rounded down to 6
+	_, wantY := diagonalEnd()
+	final := states[len(states)-1].P1
+	if final.Y != 120 {
+		t.Errorf("expected P1 Y=120 with whole-unit positions, got %f", final.Y)
+	}
+	if drift := wantY - final.Y; drift < 1 {
+		t.Errorf("expected integer rounding to drift by more than 1 unit, got %f", drift)
+	}
+	for i, s := range states {
+		if s.P1.X != math.Round(s.P1.X) || s.P1.Y != math.Round(s.P1.Y) {
+			t.Fatalf("tick %d: expected whole-unit position, got (%f, %f)", i, s.P1.X, s.P1.Y)
+		}
+	}
+}
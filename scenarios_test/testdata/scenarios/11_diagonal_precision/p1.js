@@ -0,0 +1,8 @@
+function run(state) {
+    // Face 30° (north-north-east), then move 7 every tick
+    if (state.tick === 0) {
+        turn(30);
+    } else {
+        move(7);
+    }
+}
@@ -0,0 +1,3 @@
+function run(state) {
+    // Do nothing
+}